@@ -0,0 +1,204 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// MatchType is the way a KeywordRule matches the reply message.
+type MatchType string
+
+// List of match types.
+const (
+	MatchExact  MatchType = "exact"
+	MatchPrefix MatchType = "prefix"
+	MatchRegexp MatchType = "regexp"
+)
+
+// KeywordRule represents an auto-responder rule.
+type KeywordRule struct {
+	// Name of the rule, for reference.
+	Name string `json:"name,omitempty" mapstructure:"name"`
+
+	// Match type. Defaults to MatchExact.
+	Match MatchType `json:"match,omitempty" mapstructure:"match"`
+
+	// Keywords, or regular expressions for MatchRegexp. The rule matches if any keyword does.
+	Keywords []string `json:"keywords" mapstructure:"keywords"`
+
+	// Reply, if not empty, is sent back to the number.
+	Reply string `json:"reply,omitempty" mapstructure:"reply"`
+
+	// OptOut marks the number as opted out through AutoResponder.OptOut.
+	OptOut bool `json:"opt_out,omitempty" mapstructure:"opt_out"`
+
+	// Callback, if not nil, is called with the matched reply.
+	Callback ReportHandlerFunc `json:"-" mapstructure:"-"`
+
+	patterns []*regexp.Regexp
+}
+
+// compile validates the rule and prepares its keywords.
+func (r *KeywordRule) compile() error {
+	if r.Match == "" {
+		r.Match = MatchExact
+	}
+	if len(r.Keywords) == 0 {
+		return fmt.Errorf("rule %q has no keywords", r.Name)
+	}
+
+	switch r.Match {
+	case MatchExact, MatchPrefix:
+		for i, keyword := range r.Keywords {
+			r.Keywords[i] = normalizeKeyword(keyword)
+		}
+	case MatchRegexp:
+		r.patterns = nil
+		for _, keyword := range r.Keywords {
+			pattern, err := regexp.Compile("(?i)" + width.Fold.String(keyword))
+			if err != nil {
+				return fmt.Errorf("rule %q: %v", r.Name, err)
+			}
+			r.patterns = append(r.patterns, pattern)
+		}
+	default:
+		return fmt.Errorf("rule %q has unknown match type %q", r.Name, r.Match)
+	}
+	return nil
+}
+
+// matches reports whether the normalized text matches the rule.
+func (r *KeywordRule) matches(text string) bool {
+	switch r.Match {
+	case MatchRegexp:
+		for _, pattern := range r.patterns {
+			if pattern.MatchString(text) {
+				return true
+			}
+		}
+	case MatchPrefix:
+		for _, keyword := range r.Keywords {
+			if strings.HasPrefix(text, keyword) {
+				return true
+			}
+		}
+	default:
+		for _, keyword := range r.Keywords {
+			if text == keyword {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeKeyword folds the full-width characters typed by Chinese input methods to
+// their half-width form, e.g. "ＳＴＯＰ" to "STOP", then lower-cases and trims the text.
+func normalizeKeyword(text string) string {
+	return strings.ToLower(strings.TrimSpace(width.Fold.String(text)))
+}
+
+// ParseKeywordRules parses a JSON array of rules, or an object holding the array under
+// the "rules" key, e.g. {"rules": [{"keywords": ["STOP"], "opt_out": true}]}.
+func ParseKeywordRules(r io.Reader) ([]*KeywordRule, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var rules []*KeywordRule
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		var file struct {
+			Rules []*KeywordRule `json:"rules"`
+		}
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, err
+		}
+		return file.Rules, nil
+	}
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// AutoResponder handles the reply messages matching keyword rules, e.g. STOP, HELP or YES.
+// Its HandleReply method can be used as WebhookHandler.OnReply.
+type AutoResponder struct {
+	client *Client
+	rules  []*KeywordRule
+
+	// OptOut is called with the number of replies matching a rule with OptOut set.
+	OptOut func(ctx context.Context, mobile string) error
+
+	// Fallback, if not nil, is called with the replies matching no rule.
+	Fallback ReportHandlerFunc
+}
+
+// NewAutoResponder returns a new AutoResponder sending the auto-replies through the client.
+// The rules are evaluated in order, the first matching rule wins. The rules are copied,
+// so the caller's rules are left unchanged.
+func NewAutoResponder(client *Client, rules []*KeywordRule) (*AutoResponder, error) {
+	compiled := make([]*KeywordRule, len(rules))
+	for i, rule := range rules {
+		c := *rule
+		c.Keywords = append([]string(nil), rule.Keywords...)
+		if err := c.compile(); err != nil {
+			return nil, err
+		}
+		compiled[i] = &c
+	}
+	return &AutoResponder{client: client, rules: compiled}, nil
+}
+
+// Match returns the first rule matching the text, nil if there is none.
+func (a *AutoResponder) Match(text string) *KeywordRule {
+	text = normalizeKeyword(text)
+	for _, rule := range a.rules {
+		if rule.matches(text) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// HandleReply triggers the actions of the rule matching the reply message.
+// The reply of the rule is sent even if the number is suppressed, so an opt-out can
+// be confirmed.
+func (a *AutoResponder) HandleReply(ctx context.Context, report *ReportMessage) error {
+	if report.StatusCode != StatusReplayContent {
+		return nil
+	}
+
+	rule := a.Match(report.ReplyMessage)
+	if rule == nil {
+		if a.Fallback != nil {
+			return a.Fallback(ctx, report)
+		}
+		return nil
+	}
+
+	mobile := NormalizeMobile(report.Destination)
+	if rule.OptOut && a.OptOut != nil {
+		if err := a.OptOut(ctx, mobile); err != nil {
+			return err
+		}
+	}
+	if rule.Reply != "" {
+		// The confirmation of an opt-out reaches the number just suppressed.
+		if _, err := a.client.Send(bypassSuppression(ctx), Message{Content: rule.Reply, Destination: mobile}); err != nil {
+			return err
+		}
+	}
+	if rule.Callback != nil {
+		return rule.Callback(ctx, report)
+	}
+	return nil
+}
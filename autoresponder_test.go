@@ -0,0 +1,142 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAutoResponder_Match(t *testing.T) {
+	rules, err := ParseKeywordRules(strings.NewReader(`[
+		{"name": "stop", "keywords": ["STOP", "退訂"], "opt_out": true},
+		{"name": "help", "match": "prefix", "keywords": ["help"]},
+		{"name": "yes", "match": "regexp", "keywords": ["^(y|yes|是)$"]}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseKeywordRules returned unexpected error: %v", err)
+	}
+	a, err := NewAutoResponder(nil, rules)
+	if err != nil {
+		t.Fatalf("NewAutoResponder returned unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"STOP", "stop"},
+		{" stop ", "stop"},
+		{"ＳＴＯＰ", "stop"},
+		{"ｓｔｏｐ", "stop"},
+		{"退訂", "stop"},
+		{"stop please", ""},
+		{"HELP me", "help"},
+		{"ＨＥＬＰ", "help"},
+		{"Yes", "yes"},
+		{"ＹＥＳ", "yes"},
+		{"是", "yes"},
+		{"yesterday", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if rule := a.Match(tt.in); rule != nil {
+			got = rule.Name
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q) returned rule %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeywordRules_object(t *testing.T) {
+	rules, err := ParseKeywordRules(strings.NewReader(`{"rules": [{"name": "stop", "keywords": ["STOP"], "opt_out": true}]}`))
+	if err != nil {
+		t.Fatalf("ParseKeywordRules returned unexpected error: %v", err)
+	}
+	want := []*KeywordRule{{Name: "stop", Keywords: []string{"STOP"}, OptOut: true}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ParseKeywordRules returned %+v, want %+v", rules, want)
+	}
+}
+
+func TestNewAutoResponder_copiesRules(t *testing.T) {
+	rule := &KeywordRule{Keywords: []string{"ＳＴＯＰ"}}
+	a, err := NewAutoResponder(nil, []*KeywordRule{rule})
+	if err != nil {
+		t.Fatalf("NewAutoResponder returned unexpected error: %v", err)
+	}
+	if want := (&KeywordRule{Keywords: []string{"ＳＴＯＰ"}}); !reflect.DeepEqual(rule, want) {
+		t.Errorf("NewAutoResponder modified the rule to %+v, want %+v", rule, want)
+	}
+	if a.Match("stop") == nil {
+		t.Error("Match(stop) returned nil, want a rule")
+	}
+}
+
+func TestNewAutoResponder_invalidRule(t *testing.T) {
+	tests := []*KeywordRule{
+		{Name: "empty"},
+		{Name: "type", Match: "unknown", Keywords: []string{"a"}},
+		{Name: "regexp", Match: MatchRegexp, Keywords: []string{"("}},
+	}
+
+	for _, rule := range tests {
+		if _, err := NewAutoResponder(nil, []*KeywordRule{rule}); err == nil {
+			t.Errorf("NewAutoResponder(%q) expected error to be returned", rule.Name)
+		}
+	}
+}
+
+func TestAutoResponder_HandleReply(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{
+			"MSG":  "You have been unsubscribed.",
+			"DEST": "+886987654321",
+		})
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	var optOuts []string
+	var callbacks, fallbacks int
+	a, err := NewAutoResponder(client, []*KeywordRule{
+		{Keywords: []string{"stop"}, Reply: "You have been unsubscribed.", OptOut: true},
+		{Keywords: []string{"y"}, Callback: func(ctx context.Context, report *ReportMessage) error {
+			callbacks++
+			return nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewAutoResponder returned unexpected error: %v", err)
+	}
+	a.OptOut = func(ctx context.Context, mobile string) error {
+		optOuts = append(optOuts, mobile)
+		return nil
+	}
+	a.Fallback = func(ctx context.Context, report *ReportMessage) error {
+		fallbacks++
+		return nil
+	}
+
+	for _, text := range []string{"ＳＴＯＰ", "Y", "Hello"} {
+		report := createReportMessage()
+		report.StatusCode = StatusReplayContent
+		report.ReplyMessage = text
+		if err := a.HandleReply(context.Background(), report); err != nil {
+			t.Errorf("HandleReply(%q) returned unexpected error: %v", text, err)
+		}
+	}
+
+	if want := []string{"+886987654321"}; !reflect.DeepEqual(optOuts, want) {
+		t.Errorf("OptOut received %v, want %v", optOuts, want)
+	}
+	if callbacks != 1 || fallbacks != 1 {
+		t.Errorf("Called %d callbacks and %d fallbacks, want 1 and 1", callbacks, fallbacks)
+	}
+}
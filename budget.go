@@ -0,0 +1,358 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is matched by the *BudgetExceededError returned when a send would
+// exceed a budget, e.g. errors.Is(err, every8d.ErrBudgetExceeded).
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetPeriod is the period a budget limit applies to.
+type BudgetPeriod int
+
+const (
+	// BudgetDaily limits the points spent per day.
+	BudgetDaily BudgetPeriod = iota
+
+	// BudgetMonthly limits the points spent per month.
+	BudgetMonthly
+)
+
+// String returns the name of the period.
+func (p BudgetPeriod) String() string {
+	if p == BudgetMonthly {
+		return "monthly"
+	}
+	return "daily"
+}
+
+// key returns the identifier of the period containing t, e.g. "2026-10-19" or "2026-10".
+func (p BudgetPeriod) key(t time.Time) string {
+	if p == BudgetMonthly {
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
+
+// BudgetExceededError reports a send refused because its estimated cost exceeds a budget.
+type BudgetExceededError struct {
+	Key    string
+	Period BudgetPeriod
+
+	// Limit of the period, the points spent and reserved, and the estimated cost.
+	Limit    float64
+	Spent    float64
+	Estimate float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%v: %s %s limit %.2f, spent %.2f, estimated %.2f",
+		ErrBudgetExceeded, e.Key, e.Period, e.Limit, e.Spent, e.Estimate)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetLimit is the number of points a key can spend. Zero limits are unlimited.
+type BudgetLimit struct {
+	Daily   float64 `json:"daily" yaml:"daily"`
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+}
+
+// of returns the limit of the period.
+func (l BudgetLimit) of(period BudgetPeriod) float64 {
+	if period == BudgetMonthly {
+		return l.Monthly
+	}
+	return l.Daily
+}
+
+// BudgetStore persists the points spent by key and period.
+type BudgetStore interface {
+	// Spent returns the points spent by the key in the period, e.g. "2026-10-19".
+	Spent(ctx context.Context, key, period string) (float64, error)
+
+	// Spend adds the points to those spent by the key in the period.
+	Spend(ctx context.Context, key, period string, points float64) error
+}
+
+// Budget caps the points spent by the sends of each key, e.g. a service or a campaign.
+//
+// Before a send, its cost is estimated from the segment count of the content and the
+// number of recipients, and reserved. A send whose estimate would exceed the daily or
+// monthly limit of its key is refused with a *BudgetExceededError. After the send,
+// the reservation is replaced by the actual SendResponse.Cost, recorded in the store.
+type Budget struct {
+	// Limits of the keys. Keys without a limit use DefaultLimit.
+	Limits       map[string]BudgetLimit
+	DefaultLimit BudgetLimit
+
+	// CostPerSegment is the cost of an SMS segment to a recipient. Defaults to 1 point.
+	CostPerSegment float64
+
+	// MMSCost, if positive, is the cost of an MMS to a recipient. If zero, the cost
+	// of an MMS is estimated like the cost of an SMS of its content.
+	MMSCost float64
+
+	// Location of the day and month boundaries. Defaults to the time zone of Taiwan.
+	Location *time.Location
+
+	client *Client
+	store  BudgetStore
+
+	mu       sync.Mutex
+	reserved map[budgetKey]float64
+}
+
+type budgetKey struct {
+	key    string
+	period string
+}
+
+// NewBudget returns a new Budget sending with the client, recording the points spent
+// in the store.
+func NewBudget(client *Client, store BudgetStore) *Budget {
+	return &Budget{
+		client:   client,
+		store:    store,
+		reserved: make(map[budgetKey]float64),
+	}
+}
+
+// Estimate returns the estimated cost of the SMS.
+func (b *Budget) Estimate(message Message) float64 {
+	return b.estimate(message.Destination, message.Content, false)
+}
+
+// EstimateMMS returns the estimated cost of the MMS.
+func (b *Budget) EstimateMMS(message MMS) float64 {
+	return b.estimate(message.Destination, message.Content, true)
+}
+
+func (b *Budget) estimate(destination, content string, mms bool) float64 {
+	recipients := float64(len(SplitDestination(destination)))
+	if mms && b.MMSCost > 0 {
+		return recipients * b.MMSCost
+	}
+
+	cost := b.CostPerSegment
+	if cost <= 0 {
+		cost = 1
+	}
+	return recipients * float64(SegmentCount(content)) * cost
+}
+
+// Send sends the SMS on the budget of the key.
+func (b *Budget) Send(ctx context.Context, key string, message Message) (*SendResponse, error) {
+	return b.send(ctx, key, b.Estimate(message), func() (*SendResponse, error) {
+		return b.client.Send(ctx, message)
+	})
+}
+
+// SendMMS sends the MMS on the budget of the key.
+func (b *Budget) SendMMS(ctx context.Context, key string, message MMS) (*SendResponse, error) {
+	return b.send(ctx, key, b.EstimateMMS(message), func() (*SendResponse, error) {
+		return b.client.SendMMS(ctx, message)
+	})
+}
+
+func (b *Budget) send(ctx context.Context, key string, estimate float64, send func() (*SendResponse, error)) (*SendResponse, error) {
+	periods, err := b.reserve(ctx, key, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := send()
+
+	// Record the spend and release the reservation at once, so a concurrent reserve
+	// sees one or the other.
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, period := range periods {
+		if resp != nil && resp.Cost > 0 {
+			if spendErr := b.store.Spend(ctx, key, period.period, resp.Cost); spendErr != nil && err == nil {
+				err = spendErr
+			}
+		}
+		if b.reserved[period] -= estimate; b.reserved[period] <= 0 {
+			delete(b.reserved, period)
+		}
+	}
+	return resp, err
+}
+
+// reserve reserves the estimate on the budget of the key, and returns the periods it
+// is reserved in.
+func (b *Budget) reserve(ctx context.Context, key string, estimate float64) ([]budgetKey, error) {
+	limit, ok := b.Limits[key]
+	if !ok {
+		limit = b.DefaultLimit
+	}
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	periods := make([]budgetKey, 0, 2)
+	for _, period := range []BudgetPeriod{BudgetDaily, BudgetMonthly} {
+		k := budgetKey{key: key, period: period.key(now)}
+		periods = append(periods, k)
+
+		if limit.of(period) <= 0 {
+			continue
+		}
+		spent, err := b.store.Spent(ctx, key, k.period)
+		if err != nil {
+			return nil, err
+		}
+		if spent += b.reserved[k]; spent+estimate > limit.of(period) {
+			return nil, &BudgetExceededError{
+				Key:      key,
+				Period:   period,
+				Limit:    limit.of(period),
+				Spent:    spent,
+				Estimate: estimate,
+			}
+		}
+	}
+
+	for _, k := range periods {
+		b.reserved[k] += estimate
+	}
+	return periods, nil
+}
+
+// Spent returns the points spent by the key today and this month.
+func (b *Budget) Spent(ctx context.Context, key string) (daily, monthly float64, err error) {
+	now := b.now()
+	if daily, err = b.store.Spent(ctx, key, BudgetDaily.key(now)); err != nil {
+		return 0, 0, err
+	}
+	monthly, err = b.store.Spent(ctx, key, BudgetMonthly.key(now))
+	return daily, monthly, err
+}
+
+func (b *Budget) now() time.Time {
+	loc := b.Location
+	if loc == nil {
+		loc = taipei
+	}
+	return time.Now().In(loc)
+}
+
+// MemoryBudgetStore is a BudgetStore kept in memory.
+type MemoryBudgetStore struct {
+	mu    sync.Mutex
+	spent map[budgetKey]float64
+}
+
+// NewMemoryBudgetStore returns a new MemoryBudgetStore.
+func NewMemoryBudgetStore() *MemoryBudgetStore {
+	return &MemoryBudgetStore{spent: make(map[budgetKey]float64)}
+}
+
+// Spent implements the BudgetStore interface.
+func (s *MemoryBudgetStore) Spent(_ context.Context, key, period string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.spent[budgetKey{key, period}], nil
+}
+
+// Spend implements the BudgetStore interface.
+func (s *MemoryBudgetStore) Spend(_ context.Context, key, period string, points float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.spent[budgetKey{key, period}] += points
+	return nil
+}
+
+// budgetRecord is a line of the FileBudgetStore.
+type budgetRecord struct {
+	Key    string    `json:"key"`
+	Period string    `json:"period"`
+	Points float64   `json:"points"`
+	At     time.Time `json:"at"`
+}
+
+// FileBudgetStore is a BudgetStore persisted to an append-only JSON Lines file,
+// loaded in memory when opened.
+type FileBudgetStore struct {
+	*MemoryBudgetStore
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileBudgetStore opens the store at path, creating it if needed.
+func OpenFileBudgetStore(path string) (*FileBudgetStore, error) {
+	s := &FileBudgetStore{MemoryBudgetStore: NewMemoryBudgetStore()}
+
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileBudgetStore) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		record := new(budgetRecord)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		s.MemoryBudgetStore.Spend(context.Background(), record.Key, record.Period, record.Points)
+	}
+	return scanner.Err()
+}
+
+// Spend implements the BudgetStore interface.
+func (s *FileBudgetStore) Spend(ctx context.Context, key, period string, points float64) error {
+	line, err := json.Marshal(&budgetRecord{Key: key, Period: period, Points: points, At: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.MemoryBudgetStore.Spend(ctx, key, period, points)
+}
+
+// Close closes the file.
+func (s *FileBudgetStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
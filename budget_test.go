@@ -0,0 +1,201 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBudget_Send(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,2,3,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	budget := NewBudget(client, NewMemoryBudgetStore())
+	budget.Limits = map[string]BudgetLimit{"campaign": {Daily: 10, Monthly: 100}}
+
+	ctx := context.Background()
+	message := Message{Content: strings.Repeat("世", 71), Destination: "0987654321,0912345678"}
+	if got, want := budget.Estimate(message), 4.0; got != want {
+		t.Errorf("Estimate returned %v, want %v", got, want)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := budget.Send(ctx, "campaign", message); err != nil {
+			t.Fatalf("Send %d. returned unexpected error: %v", i, err)
+		}
+	}
+
+	// 9 points spent, the next estimate of 4 points exceeds the daily limit.
+	_, err := budget.Send(ctx, "campaign", message)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Send returned %v, want %v", err, ErrBudgetExceeded)
+	}
+	want := &BudgetExceededError{Key: "campaign", Period: BudgetDaily, Limit: 10, Spent: 9, Estimate: 4}
+	if got := err.(*BudgetExceededError); *got != *want {
+		t.Errorf("Send returned %+v, want %+v", got, want)
+	}
+
+	daily, monthly, err := budget.Spent(ctx, "campaign")
+	if err != nil {
+		t.Fatalf("Spent returned unexpected error: %v", err)
+	}
+	if daily != 9 || monthly != 9 {
+		t.Errorf("Spent returned %v, %v, want 9, 9", daily, monthly)
+	}
+
+	// Other keys are unlimited.
+	if _, err := budget.Send(ctx, "other", message); err != nil {
+		t.Errorf("Send returned unexpected error: %v", err)
+	}
+}
+
+func TestBudget_Send_reserve(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	release := make(chan struct{})
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	budget := NewBudget(client, NewMemoryBudgetStore())
+	budget.DefaultLimit = BudgetLimit{Monthly: 3}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := budget.Send(context.Background(), "service", Message{Content: "Hello", Destination: "0987654321"})
+			errs <- err
+		}()
+	}
+
+	// Only the sends fitting the reserved budget reach the API.
+	exceeded := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; errors.Is(err, ErrBudgetExceeded) {
+			exceeded++
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if errors.Is(err, ErrBudgetExceeded) {
+			exceeded++
+		}
+	}
+	if exceeded != 2 {
+		t.Errorf("Send exceeded the budget %d times, want 2", exceeded)
+	}
+}
+
+// slowBudgetStore delays the spends, widening the window between a send and its spend.
+type slowBudgetStore struct {
+	*MemoryBudgetStore
+}
+
+func (s slowBudgetStore) Spend(ctx context.Context, key, period string, points float64) error {
+	time.Sleep(5 * time.Millisecond)
+	return s.MemoryBudgetStore.Spend(ctx, key, period, points)
+}
+
+func TestBudget_Send_concurrent(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	const n = 20
+	budget := NewBudget(client, slowBudgetStore{NewMemoryBudgetStore()})
+	budget.DefaultLimit = BudgetLimit{Daily: n - 1}
+
+	// The sends are staggered so the reservations race with the spends of the
+	// previous sends, which must never let an extra send through.
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := budget.Send(context.Background(), "service", Message{Content: "Hello", Destination: "0987654321"})
+			errs <- err
+		}()
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	close(errs)
+
+	exceeded := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrBudgetExceeded):
+			exceeded++
+		case err != nil:
+			t.Errorf("Send returned unexpected error: %v", err)
+		}
+	}
+	if exceeded != 1 {
+		t.Errorf("Send exceeded the budget %d times, want 1", exceeded)
+	}
+}
+
+func TestBudget_Send_error(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+	})
+
+	budget := NewBudget(client, NewMemoryBudgetStore())
+	budget.DefaultLimit = BudgetLimit{Daily: 1}
+
+	// A failed send releases its reservation.
+	for i := 0; i < 2; i++ {
+		if _, err := budget.Send(context.Background(), "service", Message{Content: "Hello", Destination: "0987654321"}); errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Send %d. returned %v", i, err)
+		}
+	}
+}
+
+func TestFileBudgetStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.jsonl")
+	ctx := context.Background()
+
+	s, err := OpenFileBudgetStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileBudgetStore returned unexpected error: %v", err)
+	}
+	s.Spend(ctx, "campaign", "2026-10-19", 2)
+	s.Spend(ctx, "campaign", "2026-10-19", 1.5)
+	s.Spend(ctx, "campaign", "2026-10", 3.5)
+	s.Close()
+
+	s, err = OpenFileBudgetStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileBudgetStore returned unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	for period, want := range map[string]float64{"2026-10-19": 3.5, "2026-10": 3.5, "2026-10-20": 0} {
+		if got, _ := s.Spent(ctx, "campaign", period); got != want {
+			t.Errorf("Spent %s returned %v, want %v", period, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+// addBudgetFlags adds the flags of the spending budget to the send command.
+func addBudgetFlags(cmd *cobra.Command) {
+	cmd.Flags().String("budget-file", "", "File recording the points spent by each budget key")
+	cmd.Flags().String("budget-key", "default", "Budget key the message is charged to, e.g. a service or a campaign")
+	cmd.Flags().Float64("daily-limit", 0, "Points the budget key can spend per day, 0 is unlimited")
+	cmd.Flags().Float64("monthly-limit", 0, "Points the budget key can spend per month, 0 is unlimited")
+}
+
+// newBudget returns the budget configured by the flags, its store and key, or nil if none is.
+func newBudget(cmd *cobra.Command) (*every8d.Budget, *every8d.FileBudgetStore, string, error) {
+	file, _ := cmd.Flags().GetString("budget-file")
+	key, _ := cmd.Flags().GetString("budget-key")
+	daily, _ := cmd.Flags().GetFloat64("daily-limit")
+	monthly, _ := cmd.Flags().GetFloat64("monthly-limit")
+
+	if file == "" {
+		// The points spent must outlive the command to cap anything.
+		for _, name := range []string{"budget-key", "daily-limit", "monthly-limit"} {
+			if cmd.Flags().Changed(name) {
+				return nil, nil, "", fmt.Errorf("--budget-file is required with --%s", name)
+			}
+		}
+		return nil, nil, "", nil
+	}
+
+	store, err := every8d.OpenFileBudgetStore(file)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	budget := every8d.NewBudget(client, store)
+	budget.Limits = map[string]every8d.BudgetLimit{key: {Daily: daily, Monthly: monthly}}
+	return budget, store, key, nil
+}
@@ -2,22 +2,95 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/minchao/go-every8d"
 	"github.com/spf13/cobra"
 )
 
+// exitLowCredit is the exit code when the credit is below a threshold.
+const exitLowCredit = 2
+
 var creditCmd = &cobra.Command{
 	Use:   "credit",
 	Short: "Query credit",
-	Long:  "Query to retrieve your account balance",
-	Run:   creditFunc,
+	Long: "Query to retrieve your account balance.\n\n" +
+		"With --threshold, exits with code 2 when the credit is below a threshold, or runs the --hook command instead.\n" +
+		"The hook receives the alert in the EVERY8D_CREDIT, EVERY8D_THRESHOLD and EVERY8D_ALERT (\"low\"|\"recovered\") environment variables.",
+	Run: creditFunc,
+}
+
+func init() {
+	creditCmd.Flags().Bool("watch", false, "Poll the credit until interrupted, printing the alerts")
+	creditCmd.Flags().Duration("interval", 5*time.Minute, "Interval between polls with --watch")
+	creditCmd.Flags().Float64Slice("threshold", nil, "Credit below which to alert, e.g. 100")
+	creditCmd.Flags().Float64("hysteresis", 0, "Margin above a threshold the credit must reach to recover from it")
+	creditCmd.Flags().String("hook", "", "Shell command run on each alert, instead of exiting")
 }
 
 func creditFunc(cmd *cobra.Command, _ []string) {
-	credit, err := client.GetCredit(context.Background())
-	if err != nil {
-		er(err)
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	thresholds, _ := cmd.Flags().GetFloat64Slice("threshold")
+	hysteresis, _ := cmd.Flags().GetFloat64("hysteresis")
+	hook, _ := cmd.Flags().GetString("hook")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	monitor := client.NewCreditMonitor(interval, thresholds...)
+	monitor.Hysteresis = hysteresis
+	monitor.OnAlert = func(alert every8d.CreditAlert) {
+		if alert.Low {
+			cmd.Printf("Credit %.2f is below %.2f\n", alert.Credit, alert.Threshold)
+		} else {
+			cmd.Printf("Credit %.2f recovered above %.2f\n", alert.Credit, alert.Threshold)
+		}
+
+		if hook == "" {
+			if alert.Low {
+				os.Exit(exitLowCredit)
+			}
+			return
+		}
+		if err := runCreditHook(ctx, hook, alert); err != nil {
+			logger.Error("credit hook failed", "error", err)
+		}
+	}
+
+	if !watch {
+		credit, err := monitor.Poll(ctx)
+		if err != nil {
+			er(err)
+		}
+		cmd.Printf("Credit: %.2f\n", credit)
+		return
+	}
+
+	monitor.OnError = func(err error) {
+		logger.Error("credit failed", "error", err)
+	}
+	monitor.Run(ctx)
+}
+
+// runCreditHook runs the hook command with the shell, with the alert in its environment.
+func runCreditHook(ctx context.Context, hook string, alert every8d.CreditAlert) error {
+	state := "recovered"
+	if alert.Low {
+		state = "low"
 	}
 
-	cmd.Printf("Credit: %.2f\n", credit)
+	c := exec.CommandContext(ctx, "sh", "-c", hook)
+	c.Env = append(os.Environ(),
+		fmt.Sprintf("EVERY8D_CREDIT=%.2f", alert.Credit),
+		fmt.Sprintf("EVERY8D_THRESHOLD=%.2f", alert.Threshold),
+		"EVERY8D_ALERT="+state,
+	)
+	c.Stdout, c.Stderr = os.Stderr, os.Stderr
+	return c.Run()
 }
@@ -19,12 +19,14 @@ func init() {
 	deliveryStatusCmd.Flags().StringP("bid", "b", "", "Batch ID")
 	deliveryStatusCmd.Flags().StringP("pno", "p", "", "Paging number")
 	deliveryStatusCmd.Flags().StringP("type", "t", "sms", "Message type (\"sms\"|\"mms\")")
+	deliveryStatusCmd.Flags().String("lang", every8d.DefaultLanguage, "Language of the status text (\"zh-TW\"|\"en\")")
 }
 
 func deliveryStatusFunc(cmd *cobra.Command, _ []string) {
 	batchID, _ := cmd.Flags().GetString("bid")
 	pageNo, _ := cmd.Flags().GetString("pno")
 	messageType, _ := cmd.Flags().GetString("type")
+	lang, _ := cmd.Flags().GetString("lang")
 
 	var resp *every8d.DeliveryStatusResponse
 	var err error
@@ -37,16 +39,22 @@ func deliveryStatusFunc(cmd *cobra.Command, _ []string) {
 		er(err)
 	}
 
+	if correlation != nil {
+		if err := every8d.RecordDeliveryStatuses(context.Background(), correlation, batchID, resp.Records); err != nil {
+			er(err)
+		}
+	}
+
 	cmd.Printf("Count: %d\n", resp.Count)
 	cmd.Println("Name\tMobile\tSendTime\tCost\tStatus\tStatusText")
 	for _, record := range resp.Records {
 		cmd.Printf("%s\t%s\t%s\t%.2f\t%v\t%s\n",
 			record.Name,
-			record.Mobile,
+			masker.Mask(record.Mobile),
 			record.SendTime,
 			record.Cost,
 			record.Status,
-			record.Status.Text(),
+			record.Status.TextIn(lang),
 		)
 	}
 }
@@ -1,11 +1,55 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+
+	"github.com/minchao/go-every8d"
 )
 
+// jsonLogs reports whether the diagnostics are logged as JSON.
+var jsonLogs bool
+
 func er(msg interface{}) {
-	fmt.Println("Error:", msg)
+	text := masker.MaskText(fmt.Sprint(msg))
+	if jsonLogs {
+		logger.Error(text)
+	} else {
+		fmt.Fprintln(os.Stderr, "Error:", text)
+	}
 	os.Exit(1)
 }
+
+// newLogger returns the logger of the diagnostics, written to stderr.
+func newLogger(level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		jsonLogs = false
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		jsonLogs = true
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q", format)
+}
+
+// newMasker returns the masker of the policy.
+func newMasker(policy, salt string) (*every8d.Masker, error) {
+	p, err := every8d.ParseMaskPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	if p == every8d.MaskHash && salt == "" {
+		return nil, errors.New("--mask-salt is required with --mask=hash")
+	}
+	return &every8d.Masker{Policy: p, Salt: []byte(salt)}, nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayCmd = &cobra.Command{
+		Use:   "replay",
+		Short: "Replay the callbacks recorded in the webhook journal",
+		Long:  "Replay the callbacks recorded by the webhook --journal-dir, printing them or delivering them to a callback URL",
+		Run:   replayFunc,
+	}
+)
+
+func init() {
+	replayCmd.Flags().String("journal-dir", "", "Directory of the journal")
+	replayCmd.Flags().Uint64("offset", 0, "Offset of the first entry to replay")
+	replayCmd.Flags().String("since", "", "Replay the entries received since the time, in RFC 3339 format")
+	replayCmd.Flags().String("url", "", "Callback URL the reports are delivered to, instead of printing them")
+	replayCmd.Flags().String("lang", every8d.DefaultLanguage, "Language of the status text (\"zh-TW\"|\"en\")")
+	replayCmd.MarkFlagRequired("journal-dir")
+}
+
+func replayFunc(cmd *cobra.Command, _ []string) {
+	dir, _ := cmd.Flags().GetString("journal-dir")
+	offset, _ := cmd.Flags().GetUint64("offset")
+	since, _ := cmd.Flags().GetString("since")
+	callbackURL, _ := cmd.Flags().GetString("url")
+	lang, _ := cmd.Flags().GetString("lang")
+
+	opts := &every8d.ReplayOptions{Offset: offset}
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			er(err)
+		}
+		opts.Since = t
+	}
+
+	if callbackURL == "" {
+		cmd.Println("OFFSET\tBatchID\tRM\tRT\tSTATUS\tSTATUS_TEXT\tSM\tMR\t")
+	}
+
+	n := 0
+	deliver := every8d.ReplayToURL(callbackURL)
+	err := every8d.ReplayJournal(context.Background(), dir, opts, func(ctx context.Context, entry *every8d.JournalEntry) error {
+		n++
+		if callbackURL != "" {
+			return deliver(ctx, entry)
+		}
+		cmd.Printf("%d\t", entry.Offset)
+		printReportRow(cmd, entry.Report, lang)
+		return nil
+	})
+	if err != nil {
+		er(err)
+	}
+
+	if callbackURL != "" {
+		cmd.Printf("Replayed: %d\n", n)
+	}
+}
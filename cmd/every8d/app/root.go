@@ -1,22 +1,68 @@
 package app
 
 import (
+	"log/slog"
+	"os"
+
 	"github.com/minchao/go-every8d"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	client *every8d.Client
+	client      *every8d.Client
+	suppression *every8d.FileSuppressionList
+	correlation *every8d.FileCorrelationStore
+	ledger      *every8d.FileLedger
+	logger      = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// masker masks the phone numbers of the outputs, logs and ledger tags.
+	masker *every8d.Masker
 
 	rootCmd = &cobra.Command{
 		Use:   "every8d",
 		Short: "EVERY8D SMS CLI tool",
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			var err error
+			if logger, err = newLogger(viper.GetString("log-level"), viper.GetString("log-format")); err != nil {
+				er(err)
+			}
+
 			username := viper.GetString("username")
 			password := viper.GetString("password")
 
 			client = every8d.NewClient(username, password, nil)
+			client.Logger = logger
+
+			if masker, err = newMasker(viper.GetString("mask"), viper.GetString("mask-salt")); err != nil {
+				er(err)
+			}
+			client.Masker = masker
+
+			if file := viper.GetString("suppression-file"); file != "" {
+				var err error
+				if suppression, err = every8d.OpenFileSuppressionList(file); err != nil {
+					er(err)
+				}
+				client.Suppression = suppression
+			}
+
+			if file := viper.GetString("correlation-file"); file != "" {
+				var err error
+				if correlation, err = every8d.OpenFileCorrelationStore(file); err != nil {
+					er(err)
+				}
+				client.Correlation = correlation
+			}
+
+			if file := viper.GetString("ledger-file"); file != "" {
+				var err error
+				if ledger, err = every8d.OpenFileLedger(file); err != nil {
+					er(err)
+				}
+				ledger.Masker = masker
+				client.Ledger = ledger
+			}
 		},
 	}
 )
@@ -28,11 +74,31 @@ func init() {
 	rootCmd.PersistentFlags().String("password", "", "EVERY8D Password")
 	viper.BindPFlag("username", rootCmd.PersistentFlags().Lookup("username"))
 	viper.BindPFlag("password", rootCmd.PersistentFlags().Lookup("password"))
+	rootCmd.PersistentFlags().String("suppression-file", "", "File of the opted-out numbers removed from every message")
+	viper.BindPFlag("suppression-file", rootCmd.PersistentFlags().Lookup("suppression-file"))
+	rootCmd.PersistentFlags().String("correlation-file", "", "File recording the sends, callbacks and polled statuses of every message")
+	viper.BindPFlag("correlation-file", rootCmd.PersistentFlags().Lookup("correlation-file"))
+	rootCmd.PersistentFlags().String("ledger-file", "", "File recording the cost of every message sent, with its tags")
+	viper.BindPFlag("ledger-file", rootCmd.PersistentFlags().Lookup("ledger-file"))
+	rootCmd.PersistentFlags().String("log-level", "info", "Level of the diagnostics logged to stderr (\"debug\"|\"info\"|\"warn\"|\"error\")")
+	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
+	rootCmd.PersistentFlags().String("log-format", "text", "Format of the diagnostics logged to stderr (\"text\"|\"json\")")
+	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
+	rootCmd.PersistentFlags().String("mask", "partial", "Masking of the phone numbers in the outputs, logs and ledger tags (\"none\"|\"partial\"|\"full\"|\"hash\")")
+	viper.BindPFlag("mask", rootCmd.PersistentFlags().Lookup("mask"))
+	rootCmd.PersistentFlags().String("mask-salt", "", "Secret salt of the hashes of --mask=hash")
+	viper.BindPFlag("mask-salt", rootCmd.PersistentFlags().Lookup("mask-salt"))
 
 	rootCmd.AddCommand(creditCmd)
 	rootCmd.AddCommand(deliveryStatusCmd)
+	rootCmd.AddCommand(replayCmd)
 	rootCmd.AddCommand(sendCmd)
 	rootCmd.AddCommand(sendMMSCmd)
+	rootCmd.AddCommand(simulateCmd)
+	rootCmd.AddCommand(suppressionCmd)
+	rootCmd.AddCommand(tailCmd)
+	rootCmd.AddCommand(traceCmd)
+	rootCmd.AddCommand(usageCmd)
 	rootCmd.AddCommand(webhookCmd)
 }
 
@@ -1,7 +1,7 @@
 package app
 
 import (
-	"context"
+	"strings"
 
 	"github.com/minchao/go-every8d"
 	"github.com/spf13/cobra"
@@ -21,6 +21,8 @@ func init() {
 	sendCmd.Flags().StringP("dest", "d", "", "Receiver's mobile number")
 	sendCmd.Flags().StringP("st", "R", "", "Reservation time")
 	sendCmd.Flags().IntP("retryTime", "r", 0, "SMS validity period of unit: minutes")
+	addBudgetFlags(sendCmd)
+	addTagFlag(sendCmd)
 }
 
 func sendFunc(cmd *cobra.Command, _ []string) {
@@ -31,16 +33,38 @@ func sendFunc(cmd *cobra.Command, _ []string) {
 	message.ReservationTime, _ = cmd.Flags().GetString("st")
 	message.RetryTime, _ = cmd.Flags().GetInt("retryTime")
 
-	resp, err := client.Send(context.Background(), message)
+	ctx := sendContext(cmd)
+	budget, store, key, err := newBudget(cmd)
 	if err != nil {
 		er(err)
 	}
 
-	cmd.Printf("Credit: %.2f\nSent: %d\nCost: %.2f\nUnsent: %d\nBatchID: %s\n",
-		resp.Credit,
-		resp.Sent,
-		resp.Cost,
-		resp.Unsent,
-		resp.BatchID,
-	)
+	var resp *every8d.SendResponse
+	if budget != nil {
+		resp, err = budget.Send(ctx, key, message)
+		store.Close()
+	} else {
+		resp, err = client.Send(ctx, message)
+	}
+	printSendResponse(cmd, resp, err)
+}
+
+// printSendResponse prints the response of a sent message, even if sending it failed
+// afterwards, so its BatchID is not lost, then exits on the error.
+func printSendResponse(cmd *cobra.Command, resp *every8d.SendResponse, err error) {
+	if resp != nil && resp.BatchID != "" {
+		cmd.Printf("Credit: %.2f\nSent: %d\nCost: %.2f\nUnsent: %d\nBatchID: %s\n",
+			resp.Credit,
+			resp.Sent,
+			resp.Cost,
+			resp.Unsent,
+			resp.BatchID,
+		)
+		if len(resp.Suppressed) > 0 {
+			cmd.Printf("Suppressed: %s\n", masker.MaskDestination(strings.Join(resp.Suppressed, ",")))
+		}
+	}
+	if err != nil {
+		er(err)
+	}
 }
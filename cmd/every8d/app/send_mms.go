@@ -1,7 +1,6 @@
 package app
 
 import (
-	"context"
 	"encoding/base64"
 	"io/ioutil"
 
@@ -26,6 +25,8 @@ func init() {
 	sendMMSCmd.Flags().StringP("image", "i", "", "Image file, binary base64 encoded")
 	sendMMSCmd.Flags().StringP("attachment", "a", "", "Image file path")
 	sendMMSCmd.Flags().StringP("type", "t", "", "Image file extension, support jpg/jpeg/png/git")
+	addBudgetFlags(sendMMSCmd)
+	addTagFlag(sendMMSCmd)
 }
 
 func sendMMSFunc(cmd *cobra.Command, _ []string) {
@@ -46,16 +47,18 @@ func sendMMSFunc(cmd *cobra.Command, _ []string) {
 		message.Attachment = base64.StdEncoding.EncodeToString(f)
 	}
 
-	resp, err := client.SendMMS(context.Background(), message)
+	ctx := sendContext(cmd)
+	budget, store, key, err := newBudget(cmd)
 	if err != nil {
 		er(err)
 	}
 
-	cmd.Printf("Credit: %.2f\nSent: %d\nCost: %.2f\nUnsent: %d\nBatchID: %s\n",
-		resp.Credit,
-		resp.Sent,
-		resp.Cost,
-		resp.Unsent,
-		resp.BatchID,
-	)
+	var resp *every8d.SendResponse
+	if budget != nil {
+		resp, err = budget.SendMMS(ctx, key, message)
+		store.Close()
+	} else {
+		resp, err = client.SendMMS(ctx, message)
+	}
+	printSendResponse(cmd, resp, err)
 }
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simulateCmd = &cobra.Command{
+		Use:   "simulate",
+		Short: "Fire fake EVERY8D callbacks at a webhook URL",
+		Long:  "Fire fake sending reports and reply messages at a webhook URL, as a single report, a sent → received → reply sequence, or a load test",
+		Run:   simulateFunc,
+	}
+)
+
+func init() {
+	simulateCmd.Flags().String("url", "", "Callback URL of the webhook")
+	simulateCmd.Flags().StringP("bid", "b", "", "Batch ID, random if empty")
+	simulateCmd.Flags().StringP("dest", "d", "+886987654321", "Receiver's mobile number")
+	simulateCmd.Flags().IntP("status", "s", int(every8d.StatusMessageReceived), "Status code of the sending report")
+	simulateCmd.Flags().String("reply", "", "Reply message, sends a reply instead of a sending report")
+	simulateCmd.Flags().Bool("sequence", false, "Send the sent → received → reply sequence, the reply if --reply is set")
+	simulateCmd.Flags().Duration("interval", 0, "Interval between the reports of a sequence")
+	simulateCmd.Flags().IntP("count", "n", 0, "Number of reports of a load test, each with a random batch ID")
+	simulateCmd.Flags().IntP("concurrency", "c", 1, "Number of reports delivered in parallel by a load test")
+	simulateCmd.MarkFlagRequired("url")
+}
+
+func simulateFunc(cmd *cobra.Command, _ []string) {
+	callbackURL, _ := cmd.Flags().GetString("url")
+	batchID, _ := cmd.Flags().GetString("bid")
+	destination, _ := cmd.Flags().GetString("dest")
+	status, _ := cmd.Flags().GetInt("status")
+	reply, _ := cmd.Flags().GetString("reply")
+	sequence, _ := cmd.Flags().GetBool("sequence")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	count, _ := cmd.Flags().GetInt("count")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+	simulator := &every8d.Simulator{
+		URL:         callbackURL,
+		Interval:    interval,
+		Concurrency: concurrency,
+	}
+
+	newReport := func(batchID string) *every8d.ReportMessage {
+		if reply != "" {
+			return every8d.NewReplyReport(batchID, destination, reply)
+		}
+		return every8d.NewDeliveryReport(batchID, destination, every8d.StatusCode(status))
+	}
+
+	if count > 0 {
+		result := simulator.Load(context.Background(), count, func(int) *every8d.ReportMessage {
+			return newReport(every8d.NewBatchID())
+		})
+		cmd.Printf("Delivered: %d\n", result.Delivered)
+		cmd.Printf("Failed: %d\n", result.Failed)
+		cmd.Printf("Duration: %v\n", result.Duration.Round(time.Millisecond))
+		cmd.Printf("Rate: %.2f/s\n", result.Rate())
+		if result.Err != nil {
+			er(result.Err)
+		}
+		return
+	}
+
+	if batchID == "" {
+		batchID = every8d.NewBatchID()
+	}
+	reports := []*every8d.ReportMessage{newReport(batchID)}
+	if sequence {
+		reports = every8d.ReportSequence(batchID, destination, reply)
+	}
+
+	if err := simulator.Run(context.Background(), reports); err != nil {
+		er(err)
+	}
+	for _, report := range reports {
+		cmd.Printf("%s\t%s\t%s\t%d\t%s\n",
+			report.BatchID,
+			masker.Mask(report.Destination),
+			report.ReportTime,
+			report.StatusCode,
+			report.ReplyMessage,
+		)
+	}
+}
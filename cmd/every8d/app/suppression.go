@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	suppressionCmd = &cobra.Command{
+		Use:   "suppression",
+		Short: "Manage the opted-out numbers of the --suppression-file",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			rootCmd.PersistentPreRun(cmd, args)
+			if suppression == nil {
+				er(errors.New("--suppression-file is required"))
+			}
+		},
+	}
+
+	suppressionListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the suppressed numbers",
+		Run:   suppressionListFunc,
+	}
+
+	suppressionAddCmd = &cobra.Command{
+		Use:   "add <mobile>...",
+		Short: "Add numbers to the suppression list",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   suppressionAddFunc,
+	}
+
+	suppressionRemoveCmd = &cobra.Command{
+		Use:   "remove <mobile>...",
+		Short: "Remove numbers from the suppression list",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   suppressionRemoveFunc,
+	}
+
+	suppressionImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import numbers, one per line, into the suppression list",
+		Args:  cobra.ExactArgs(1),
+		Run:   suppressionImportFunc,
+	}
+
+	suppressionExportCmd = &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export the suppression list, to stdout if no file is given",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   suppressionExportFunc,
+	}
+)
+
+func init() {
+	suppressionCmd.AddCommand(suppressionListCmd)
+	suppressionCmd.AddCommand(suppressionAddCmd)
+	suppressionCmd.AddCommand(suppressionRemoveCmd)
+	suppressionCmd.AddCommand(suppressionImportCmd)
+	suppressionCmd.AddCommand(suppressionExportCmd)
+}
+
+func suppressionListFunc(cmd *cobra.Command, _ []string) {
+	mobiles, err := suppression.List(context.Background())
+	if err != nil {
+		er(err)
+	}
+
+	for _, mobile := range mobiles {
+		cmd.Println(mobile)
+	}
+}
+
+func suppressionAddFunc(cmd *cobra.Command, args []string) {
+	for _, mobile := range args {
+		if err := suppression.Add(context.Background(), mobile); err != nil {
+			er(err)
+		}
+	}
+}
+
+func suppressionRemoveFunc(cmd *cobra.Command, args []string) {
+	for _, mobile := range args {
+		if err := suppression.Remove(context.Background(), mobile); err != nil {
+			er(err)
+		}
+	}
+}
+
+func suppressionImportFunc(cmd *cobra.Command, args []string) {
+	f, err := os.Open(args[0])
+	if err != nil {
+		er(err)
+	}
+	defer f.Close()
+
+	n, err := every8d.ImportSuppressionList(context.Background(), suppression, f)
+	if err != nil {
+		er(err)
+	}
+
+	cmd.Printf("Imported: %d\n", n)
+}
+
+func suppressionExportFunc(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		if err := every8d.ExportSuppressionList(context.Background(), suppression, cmd.OutOrStdout()); err != nil {
+			er(err)
+		}
+		return
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		er(err)
+	}
+	defer f.Close()
+
+	if err := every8d.ExportSuppressionList(context.Background(), suppression, f); err != nil {
+		er(err)
+	}
+}
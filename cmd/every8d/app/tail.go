@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tailCmd = &cobra.Command{
+		Use:   "tail",
+		Short: "Watch the callbacks received by a webhook server",
+		Long:  "Connect to the /events stream of a webhook server and print the callbacks as they arrive",
+		Run:   tailFunc,
+	}
+)
+
+func init() {
+	tailCmd.Flags().String("url", "http://localhost:8080/events", "Events URL of the webhook server")
+	tailCmd.Flags().String("token", "", "Secret token of the webhook server, see webhook --token")
+	tailCmd.Flags().StringP("bid", "b", "", "Only print the reports of the batch ID")
+	tailCmd.Flags().IntSliceP("status", "s", nil, "Only print the reports of the status codes, e.g. 100,999")
+	tailCmd.Flags().String("lang", every8d.DefaultLanguage, "Language of the status text (\"zh-TW\"|\"en\")")
+}
+
+func tailFunc(cmd *cobra.Command, _ []string) {
+	eventsURL, _ := cmd.Flags().GetString("url")
+	batchID, _ := cmd.Flags().GetString("bid")
+	statuses, _ := cmd.Flags().GetIntSlice("status")
+	lang, _ := cmd.Flags().GetString("lang")
+	token, _ := cmd.Flags().GetString("token")
+
+	if token != "" {
+		u, err := url.Parse(eventsURL)
+		if err != nil {
+			er(err)
+		}
+		query := u.Query()
+		query.Set("token", token)
+		u.RawQuery = query.Encode()
+		eventsURL = u.String()
+	}
+
+	filter := &every8d.ReportFilter{BatchID: batchID}
+	for _, status := range statuses {
+		filter.Statuses = append(filter.Statuses, every8d.StatusCode(status))
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "BatchID\tRM\tRT\tSTATUS\tSTATUS_TEXT\tSM\tMR\t")
+	err := every8d.SubscribeEvents(context.Background(), nil, eventsURL, filter, func(report *every8d.ReportMessage) error {
+		printReportRow(cmd, report, lang)
+		return nil
+	})
+	if err != nil {
+		er(err)
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	traceCmd = &cobra.Command{
+		Use:   "trace",
+		Short: "Show what happened to a message",
+		Long:  "Show the timeline of the sends, callbacks and polled statuses recorded in the --correlation-file",
+		Run:   traceFunc,
+	}
+)
+
+func init() {
+	traceCmd.Flags().StringP("bid", "b", "", "Batch ID")
+	traceCmd.Flags().StringP("mobile", "m", "", "Mobile number")
+	traceCmd.Flags().String("mr", "", "Message record no")
+	traceCmd.Flags().String("lang", every8d.DefaultLanguage, "Language of the status text (\"zh-TW\"|\"en\")")
+}
+
+func traceFunc(cmd *cobra.Command, _ []string) {
+	batchID, _ := cmd.Flags().GetString("bid")
+	mobile, _ := cmd.Flags().GetString("mobile")
+	messageNo, _ := cmd.Flags().GetString("mr")
+	lang, _ := cmd.Flags().GetString("lang")
+
+	if correlation == nil {
+		er("--correlation-file is required")
+	}
+
+	ctx := context.Background()
+	var timelines []*every8d.RecipientTimeline
+	var err error
+	switch {
+	case batchID != "":
+		timelines, err = correlation.ByBatch(ctx, batchID)
+	case mobile != "":
+		timelines, err = correlation.ByMobile(ctx, mobile)
+	case messageNo != "":
+		timelines, err = correlation.ByMessageNo(ctx, messageNo)
+	default:
+		er("one of --bid, --mobile or --mr is required")
+	}
+	if err != nil {
+		er(err)
+	}
+
+	for _, timeline := range timelines {
+		status := timeline.Status()
+		cmd.Printf("%s\t%s\t%s\t%d\t%s\n", timeline.BatchID, masker.Mask(timeline.Mobile), timeline.MessageNo, status, status.TextIn(lang))
+		for _, event := range timeline.Events {
+			cmd.Printf("\t%s\t%s\t%d\t%s\t%s\n",
+				event.Time.Format("2006/01/02 15:04:05"),
+				event.Source,
+				event.Status,
+				event.Status.TextIn(lang),
+				event.Reply,
+			)
+		}
+	}
+}
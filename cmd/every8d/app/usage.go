@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageCmd = &cobra.Command{
+		Use:   "usage",
+		Short: "Report the points spent",
+		Long:  "Report the points spent by the messages recorded in the --ledger-file, by tag and period",
+		Run:   usageFunc,
+	}
+)
+
+func init() {
+	usageCmd.Flags().StringSlice("by", nil, "Tags the usage is split by, e.g. team,campaign")
+	usageCmd.Flags().String("period", "month", "Period the usage is split by (\"day\"|\"month\"|\"total\")")
+	usageCmd.Flags().String("from", "", "First day of the report, e.g. 2026-10-01")
+	usageCmd.Flags().String("to", "", "Day after the last day of the report, e.g. 2026-11-01")
+	usageCmd.Flags().String("format", "table", "Output format (\"table\"|\"csv\"|\"json\")")
+	usageCmd.Flags().Bool("reconcile", false, "Reconcile the costs with the delivery statuses first")
+}
+
+// addTagFlag adds the flag of the cost tags to the send command.
+func addTagFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToString("tag", nil, "Tag recorded with the cost in the --ledger-file, e.g. --tag team=growth")
+}
+
+// sendContext returns the context of the send, carrying the tags of the flags.
+func sendContext(cmd *cobra.Command) context.Context {
+	tags, _ := cmd.Flags().GetStringToString("tag")
+	if len(tags) == 0 {
+		return context.Background()
+	}
+	return every8d.WithTags(context.Background(), tags)
+}
+
+func usageFunc(cmd *cobra.Command, _ []string) {
+	groupBy, _ := cmd.Flags().GetStringSlice("by")
+	period, _ := cmd.Flags().GetString("period")
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	format, _ := cmd.Flags().GetString("format")
+	reconcile, _ := cmd.Flags().GetBool("reconcile")
+
+	if ledger == nil {
+		er("--ledger-file is required")
+	}
+	defer ledger.Close()
+
+	query := &every8d.UsageQuery{GroupBy: groupBy, Period: period}
+	if period == "total" {
+		query.Period = every8d.UsageTotal
+	}
+	var err error
+	if query.From, err = parseUsageDay(from); err != nil {
+		er(err)
+	}
+	if query.To, err = parseUsageDay(to); err != nil {
+		er(err)
+	}
+
+	ctx := context.Background()
+	if reconcile {
+		// The batches failing to be reconciled are reported at their estimated cost.
+		n, err := client.ReconcileLedger(ctx, ledger)
+		if err != nil {
+			logger.Error("reconcile failed", "error", masker.MaskText(err.Error()))
+		}
+		logger.Info("reconciled", "batches", n)
+	}
+
+	rows, err := every8d.Usage(ctx, ledger, query)
+	if err != nil {
+		er(err)
+	}
+
+	out := cmd.OutOrStdout()
+	switch format {
+	case "csv":
+		err = every8d.WriteUsageCSV(out, rows, groupBy)
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rows)
+	case "table":
+		header := append([]string{"PERIOD"}, upper(groupBy)...)
+		fmt.Fprintln(out, strings.Join(append(header, "BATCHES", "SENT", "UNSENT", "COST", "RECONCILED_COST"), "\t"))
+		for _, row := range rows {
+			fields := []string{row.Period}
+			for _, name := range groupBy {
+				fields = append(fields, row.Tags[name])
+			}
+			reconciled := "-"
+			if row.Reconciled > 0 {
+				reconciled = fmt.Sprintf("%.2f (%d/%d)", row.ReconciledCost, row.Reconciled, row.Batches)
+			}
+			fmt.Fprintf(out, "%s\t%d\t%d\t%d\t%.2f\t%s\n",
+				strings.Join(fields, "\t"), row.Batches, row.Sent, row.Unsent, row.Cost, reconciled)
+		}
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		er(err)
+	}
+}
+
+// parseUsageDay parses a day in the time zone of Taiwan, zero if day is empty.
+func parseUsageDay(day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", day, time.FixedZone("Asia/Taipei", 8*60*60))
+}
+
+func upper(s []string) []string {
+	u := make([]string, len(s))
+	for i, v := range s {
+		u[i] = strings.ToUpper(v)
+	}
+	return u
+}
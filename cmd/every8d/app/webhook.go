@@ -1,11 +1,20 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/minchao/go-every8d"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -18,32 +27,425 @@ var (
 
 func init() {
 	webhookCmd.Flags().IntP("port", "p", 8080, "HTTP Server Port")
+	webhookCmd.Flags().String("addr", "", "Listen address, e.g. 127.0.0.1:8080, overrides --port")
+	webhookCmd.Flags().String("path", "/callback", "Path of the callback URL")
+	webhookCmd.Flags().String("tls-cert", "", "TLS certificate file, serves HTTPS with --tls-key")
+	webhookCmd.Flags().String("tls-key", "", "TLS private key file")
+	webhookCmd.Flags().Duration("read-timeout", 10*time.Second, "Maximum duration for reading a request")
+	webhookCmd.Flags().Duration("write-timeout", 10*time.Second, "Maximum duration for writing a response, except the event streams")
+	webhookCmd.Flags().Duration("shutdown-timeout", 30*time.Second, "Maximum duration to drain the in-flight callbacks on SIGINT or SIGTERM")
+	webhookCmd.Flags().Int64("max-body-size", 1<<20, "Maximum size in bytes of a callback body")
+	webhookCmd.Flags().String("lang", every8d.DefaultLanguage, "Language of the status text (\"zh-TW\"|\"en\")")
+	webhookCmd.Flags().String("token", "", "Secret token expected in the callback URL, e.g. /callback/<token> or /callback?token=<token>")
+	webhookCmd.Flags().String("token-param", "token", "Query parameter carrying the secret token")
+	webhookCmd.Flags().StringSlice("allow-cidr", nil, "Allowed source networks of callbacks, e.g. 203.0.113.0/24")
+	webhookCmd.Flags().StringSlice("trusted-proxy", nil, "Networks of reverse proxies whose X-Forwarded-For header is trusted")
+	webhookCmd.Flags().Duration("dedup-window", 0, "Window during which repeated reports are duplicates, 0 disables de-duplication")
+	webhookCmd.Flags().String("dedup-file", "", "File persisting the handled reports for de-duplication")
+	webhookCmd.Flags().String("rules", "", "YAML or JSON file of keyword rules answering the reply messages")
+	webhookCmd.Flags().String("journal-dir", "", "Directory of the journal durably recording every callback")
+	webhookCmd.Flags().Int64("journal-max-size", 64<<20, "Size in bytes at which the journal files are rotated")
+	webhookCmd.Flags().StringArray("forward", nil, "Sink every report is forwarded to: an http(s) URL, file:<path> or exec:<command>")
+	webhookCmd.Flags().StringArray("forward-delivery", nil, "Sink the sending reports are forwarded to, see --forward")
+	webhookCmd.Flags().StringArray("forward-reply", nil, "Sink the reply messages are forwarded to, see --forward")
+	webhookCmd.Flags().Int("forward-retries", 3, "Retries of a failed forward before the report is skipped for that sink")
+	webhookCmd.Flags().String("forward-queue-dir", "", "Directory of the durable queue of the forwarded reports, required with --forward")
+	webhookCmd.Flags().Bool("events", false, "Serve the callbacks as Server-Sent Events on /events, behind the --token and --allow-cidr checks")
+	webhookCmd.Flags().Bool("metrics", false, "Serve the Prometheus metrics on /metrics, behind the --token and --allow-cidr checks")
+	webhookCmd.Flags().Bool("dashboard", false, "Serve a web dashboard of the callbacks and credit on /dashboard/, behind the --token and --allow-cidr checks")
+	webhookCmd.Flags().String("dashboard-token", "", "Secret token of the dashboard, e.g. /dashboard/?token=<token>, instead of the --token and --allow-cidr checks")
 }
 
 func webhookFunc(cmd *cobra.Command, _ []string) {
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		report, err := every8d.ParseReportMessage(r)
+	lang, _ := cmd.Flags().GetString("lang")
+	out := newWebhookOutput(cmd, lang, jsonLogs)
+
+	printReport := func(ctx context.Context, report *every8d.ReportMessage) error {
+		out.report(ctx, report)
+		return nil
+	}
+
+	auth, err := newWebhookAuthenticator(cmd)
+	if err != nil {
+		er(err)
+	}
+
+	dedup, err := newDeduplicator(cmd)
+	if err != nil {
+		er(err)
+	}
+
+	journal, err := newJournal(cmd)
+	if err != nil {
+		er(err)
+	}
+	if journal != nil {
+		defer journal.Close()
+	}
+
+	fanout, err := newFanout(cmd)
+	if err != nil {
+		er(err)
+	}
+	if fanout != nil {
+		defer fanout.Close()
+
+		queueJournal, err := newForwardQueueJournal(cmd)
+		if err != nil {
+			er(err)
+		}
+		defer queueJournal.Close()
+
+		// Acknowledge the callbacks once queued, and forward them in the background.
+		queue := every8d.NewSinkQueue(fanout, queueJournal)
+		if queue.MaxRetries, _ = cmd.Flags().GetInt("forward-retries"); queue.MaxRetries == 0 {
+			queue.MaxRetries = -1
+		}
+		queue.OnError = func(err *every8d.SinkError, _ *every8d.JournalEntry) {
+			out.error(err, nil)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			if err := queue.Run(ctx); err != nil {
+				out.error(err, nil)
+			}
+		}()
+		defer func() {
+			cancel()
+			<-stopped
+		}()
+
+		next := printReport
+		printReport = func(ctx context.Context, report *every8d.ReportMessage) error {
+			if err := next(ctx, report); err != nil || every8d.IsDuplicate(ctx) {
+				return err
+			}
+			return queue.Deliver(ctx, report)
+		}
+	}
+
+	serveEvents, _ := cmd.Flags().GetBool("events")
+	var dashboard *every8d.Dashboard
+	if ok, _ := cmd.Flags().GetBool("dashboard"); ok {
+		dashboard = every8d.NewDashboard(client, 0)
+		dashboard.Lang = lang
+		dashboard.Masker = masker
+		if serveEvents {
+			dashboard.EventsURL = "/events"
+		}
+		next := printReport
+		printReport = func(ctx context.Context, report *every8d.ReportMessage) error {
+			if err := next(ctx, report); err != nil || every8d.IsDuplicate(ctx) {
+				return err
+			}
+			return dashboard.Record(ctx, report)
+		}
+	}
+
+	if correlation != nil {
+		defer correlation.Close()
+		next := printReport
+		printReport = func(ctx context.Context, report *every8d.ReportMessage) error {
+			if err := next(ctx, report); err != nil {
+				return err
+			}
+			return correlation.RecordReport(ctx, report)
+		}
+	}
+
+	onReply := printReport
+	if responder, err := newAutoResponder(cmd); err != nil {
+		er(err)
+	} else if responder != nil {
+		responder.OptOut = func(ctx context.Context, mobile string) error {
+			out.info("Opt-out", "mobile", masker.Mask(mobile))
+			if suppression != nil {
+				return suppression.Add(ctx, mobile)
+			}
+			return nil
+		}
+		onReply = func(ctx context.Context, report *every8d.ReportMessage) error {
+			if err := printReport(ctx, report); err != nil || every8d.IsDuplicate(ctx) {
+				return err
+			}
+			return responder.HandleReply(ctx, report)
+		}
+	}
+
+	var events *every8d.EventStream
+	if serveEvents {
+		events = every8d.NewEventStream()
+		events.Masker = masker
+	}
+	var metrics *every8d.PrometheusMetrics
+	if ok, _ := cmd.Flags().GetBool("metrics"); ok {
+		metrics = every8d.NewPrometheusMetrics()
+		client.Metrics = metrics
+	}
+	maxBodySize, _ := cmd.Flags().GetInt64("max-body-size")
+
+	handler := &every8d.WebhookHandler{
+		Authenticator:    auth,
+		MaxBodySize:      maxBodySize,
+		Journal:          journal,
+		Events:           events,
+		Deduplicator:     dedup,
+		OnDeliveryReport: printReport,
+		OnReply:          onReply,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			code := err.(*every8d.WebhookError).StatusCode
+			var rejections *every8d.WebhookRejections
+			if code == http.StatusUnauthorized || code == http.StatusForbidden {
+				r := auth.Rejections()
+				rejections = &r
+			}
+			out.error(err, rejections)
+			http.Error(w, http.StatusText(code), code)
+		},
+	}
+
+	if metrics != nil {
+		handler.Metrics = metrics
+	}
+
+	path, _ := cmd.Flags().GetString("path")
+	path = "/" + strings.Trim(path, "/")
+
+	srv := newWebhookServer(cmd, out)
+	srv.mux.Handle(path, handler)
+	if auth != nil && auth.Token != "" {
+		srv.mux.Handle(path+"/", handler)
+	}
+	if serveEvents {
+		srv.mux.Handle("/events", authenticated(auth, srv.stream(events)))
+	}
+	if metrics != nil {
+		srv.mux.Handle("/metrics", authenticated(auth, metrics))
+	}
+
+	if dashboard != nil {
+		dashboardAuth, err := newDashboardAuthenticator(cmd, auth)
 		if err != nil {
-			cmd.Printf("Error: %v\n", err)
-			return
+			er(err)
 		}
+		srv.mux.Handle("/dashboard/", dashboardAuth.Handler(dashboard))
+	}
 
-		cmd.Printf("%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
-			report.BatchID,
-			report.Destination,
-			report.ReportTime,
-			report.StatusCode,
-			report.StatusCode.Text(),
-			report.ReplyMessage,
-			report.MessageNo,
-		)
-	})
+	if err := srv.run(); err != nil {
+		er(err)
+	}
+}
+
+// printReportRow prints the report as a row of the webhook table.
+func printReportRow(cmd *cobra.Command, report *every8d.ReportMessage, lang string) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+		report.BatchID,
+		masker.Mask(report.Destination),
+		report.ReportTime,
+		report.StatusCode,
+		report.StatusCode.TextIn(lang),
+		report.ReplyMessage,
+		report.MessageNo,
+	)
+}
 
-	port, _ := cmd.Flags().GetInt("port")
+// newJournal returns the journal configured by the flags, or nil if none is.
+func newJournal(cmd *cobra.Command) (*every8d.Journal, error) {
+	dir, _ := cmd.Flags().GetString("journal-dir")
+	maxSize, _ := cmd.Flags().GetInt64("journal-max-size")
+
+	if dir == "" {
+		return nil, nil
+	}
+	journal, err := every8d.OpenJournal(dir, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// newFanout returns the fan-out to the sinks configured by the flags, or nil if none is.
+func newFanout(cmd *cobra.Command) (*every8d.Fanout, error) {
+
+	fanout := new(every8d.Fanout)
+	for _, route := range []struct {
+		flag       string
+		categories []every8d.StatusCategory
+	}{
+		{"forward", nil},
+		{"forward-delivery", every8d.DeliveryCategories},
+		{"forward-reply", []every8d.StatusCategory{every8d.CategoryReply}},
+	} {
+		specs, _ := cmd.Flags().GetStringArray(route.flag)
+		for _, spec := range specs {
+			sink, err := newSink(spec)
+			if err != nil {
+				fanout.Close()
+				return nil, err
+			}
+			fanout.Add(sink, route.categories...)
+		}
+	}
+
+	if fanout.Sinks() == 0 {
+		return nil, nil
+	}
+	return fanout, nil
+}
+
+// newForwardQueueJournal returns the journal of the queue of the forwarded reports.
+func newForwardQueueJournal(cmd *cobra.Command) (*every8d.Journal, error) {
+	dir, _ := cmd.Flags().GetString("forward-queue-dir")
+	if dir == "" {
+		return nil, errors.New("--forward-queue-dir is required to forward the reports")
+	}
+	journal, err := every8d.OpenJournal(dir, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// newSink returns the sink of the spec: an http(s) URL, file:<path> or exec:<command>.
+// The HTTP sinks do not retry themselves, the forward queue does.
+func newSink(spec string) (every8d.Sink, error) {
+	switch {
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return &every8d.HTTPSink{URL: spec, MaxRetries: -1}, nil
+	case strings.HasPrefix(spec, "file:"):
+		return every8d.OpenFileSink(strings.TrimPrefix(spec, "file:"))
+	case strings.HasPrefix(spec, "exec:"):
+		args := strings.Fields(strings.TrimPrefix(spec, "exec:"))
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing command in sink %q", spec)
+		}
+		return every8d.NewCommandSink(args[0], args[1:]...), nil
+	}
+	return nil, fmt.Errorf("unknown sink %q", spec)
+}
+
+// authenticated returns h behind the authenticator, or h itself if there is none.
+func authenticated(auth *every8d.WebhookAuthenticator, h http.Handler) http.Handler {
+	if auth == nil {
+		return h
+	}
+	return auth.Handler(h)
+}
+
+// newWebhookAuthenticator returns the authenticator configured by the flags, or nil if none is.
+func newWebhookAuthenticator(cmd *cobra.Command) (*every8d.WebhookAuthenticator, error) {
+	token, _ := cmd.Flags().GetString("token")
+	tokenParam, _ := cmd.Flags().GetString("token-param")
+	allowCIDRs, _ := cmd.Flags().GetStringSlice("allow-cidr")
+	trustedProxies, _ := cmd.Flags().GetStringSlice("trusted-proxy")
+
+	if token == "" && len(allowCIDRs) == 0 {
+		return nil, nil
+	}
+
+	allowed, err := every8d.ParseCIDRs(allowCIDRs...)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := every8d.ParseCIDRs(trustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &every8d.WebhookAuthenticator{
+		Token:           token,
+		TokenParam:      tokenParam,
+		AllowedNetworks: allowed,
+		TrustedProxies:  proxies,
+	}, nil
+}
+
+// newDashboardAuthenticator returns the authenticator of the dashboard: the token of
+// --dashboard-token if set, otherwise the authenticator of the callbacks.
+func newDashboardAuthenticator(cmd *cobra.Command, auth *every8d.WebhookAuthenticator) (*every8d.WebhookAuthenticator, error) {
+	token, _ := cmd.Flags().GetString("dashboard-token")
+	tokenParam, _ := cmd.Flags().GetString("token-param")
+
+	if token != "" {
+		return &every8d.WebhookAuthenticator{Token: token, TokenParam: tokenParam}, nil
+	}
+	if auth == nil {
+		return nil, errors.New("--dashboard requires --dashboard-token, --token or --allow-cidr")
+	}
+	return auth, nil
+}
+
+// newDeduplicator returns the deduplicator configured by the flags, or nil if none is.
+func newDeduplicator(cmd *cobra.Command) (*every8d.Deduplicator, error) {
+	window, _ := cmd.Flags().GetDuration("dedup-window")
+	file, _ := cmd.Flags().GetString("dedup-file")
+
+	if window <= 0 && file == "" {
+		return nil, nil
+	}
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+
+	dedup := &every8d.Deduplicator{Window: window, DeliverDuplicates: true}
+	if file != "" {
+		store, err := every8d.OpenFileDedupStore(file, 0)
+		if err != nil {
+			return nil, err
+		}
+		dedup.Store = store
+	}
+	return dedup, nil
+}
+
+// newAutoResponder returns the auto-responder of the rules file, or nil if there is none.
+// Without a rules file, the opt-out keywords are handled when a suppression list is configured.
+//
+// The file holds a list of rules under the "rules" key, e.g. in YAML:
+//
+//	rules:
+//	  - name: stop
+//	    keywords: ["STOP", "退訂"]
+//	    reply: You have been unsubscribed.
+//	    opt_out: true
+//
+// A JSON file may also hold a bare array of rules, see every8d.ParseKeywordRules.
+func newAutoResponder(cmd *cobra.Command) (*every8d.AutoResponder, error) {
+	file, _ := cmd.Flags().GetString("rules")
+	if file == "" {
+		// Honour the opt-out keywords when a suppression list is configured.
+		if suppression != nil {
+			return every8d.NewAutoResponder(client, []*every8d.KeywordRule{every8d.OptOutRule("")})
+		}
+		return nil, nil
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	} else {
+		// Read the other formats with viper, then parse them like a JSON file.
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(v.AllSettings())
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
 
-	cmd.Printf("Starting HTTP server on :%d\n", port)
-	cmd.Println("BatchID\tRM\tRT\tSTATUS\tSTATUS_TEXT\tSM\tMR\t")
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
-		cmd.Printf("ListenAndServe error: %v", err)
+	rules, err := every8d.ParseKeywordRules(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
 	}
+	return every8d.NewAutoResponder(client, rules)
 }
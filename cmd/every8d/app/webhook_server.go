@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/minchao/go-every8d"
+	"github.com/spf13/cobra"
+)
+
+// webhookOutput prints the callbacks to stdout, as a table or as JSON lines, and logs
+// the other webhook activity to stderr as diagnostics.
+type webhookOutput struct {
+	cmd  *cobra.Command
+	lang string
+	json bool
+}
+
+func newWebhookOutput(cmd *cobra.Command, lang string, json bool) *webhookOutput {
+	return &webhookOutput{cmd: cmd, lang: lang, json: json}
+}
+
+// webhookReportLine is a callback printed as a JSON line.
+type webhookReportLine struct {
+	Time       time.Time `json:"time"`
+	BatchID    string    `json:"batch_id"`
+	Mobile     string    `json:"mobile"`
+	ReportTime string    `json:"report_time"`
+	Status     int       `json:"status"`
+	StatusText string    `json:"status_text"`
+	Reply      string    `json:"reply"`
+	MessageNo  string    `json:"message_no"`
+	Duplicate  bool      `json:"duplicate"`
+}
+
+// header prints the header of the table.
+func (o *webhookOutput) header() {
+	if !o.json {
+		fmt.Fprintln(o.cmd.OutOrStdout(), "BatchID\tRM\tRT\tSTATUS\tSTATUS_TEXT\tSM\tMR\t")
+	}
+}
+
+func (o *webhookOutput) report(ctx context.Context, report *every8d.ReportMessage) {
+	if o.json {
+		json.NewEncoder(o.cmd.OutOrStdout()).Encode(&webhookReportLine{
+			Time:       time.Now(),
+			BatchID:    report.BatchID,
+			Mobile:     masker.Mask(report.Destination),
+			ReportTime: report.ReportTime,
+			Status:     int(report.StatusCode),
+			StatusText: report.StatusCode.TextIn(o.lang),
+			Reply:      report.ReplyMessage,
+			MessageNo:  report.MessageNo,
+			Duplicate:  every8d.IsDuplicate(ctx),
+		})
+		return
+	}
+
+	if every8d.IsDuplicate(ctx) {
+		fmt.Fprintf(o.cmd.OutOrStdout(), "Duplicate: %s\t%s\t%d\n", report.BatchID, masker.Mask(report.Destination), report.StatusCode)
+		return
+	}
+	printReportRow(o.cmd, report, o.lang)
+}
+
+func (o *webhookOutput) error(err error, rejections *every8d.WebhookRejections) {
+	args := []any{"error", masker.MaskText(err.Error())}
+	if rejections != nil {
+		args = append(args, "invalid_token", rejections.InvalidToken, "forbidden_address", rejections.ForbiddenAddress)
+	}
+	logger.Error("callback failed", args...)
+}
+
+// info logs a message with key-value pairs.
+func (o *webhookOutput) info(msg string, args ...any) {
+	logger.Info(msg, args...)
+}
+
+// request logs an HTTP request, at the debug level when printing a table.
+func (o *webhookOutput) request(r *http.Request, status int, latency time.Duration) {
+	level := slog.LevelDebug
+	if o.json {
+		level = slog.LevelInfo
+	}
+	logger.Log(r.Context(), level, "request",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+		"latency_ms", float64(latency.Microseconds())/1000,
+		"remote_addr", r.RemoteAddr,
+	)
+}
+
+// webhookServer serves the webhook with timeouts, optional TLS, a health check,
+// and a graceful shutdown on SIGINT or SIGTERM.
+type webhookServer struct {
+	cmd *cobra.Command
+	out *webhookOutput
+	mux *http.ServeMux
+
+	draining atomic.Bool
+	stopped  chan struct{}
+}
+
+func newWebhookServer(cmd *cobra.Command, out *webhookOutput) *webhookServer {
+	s := &webhookServer{
+		cmd:     cmd,
+		out:     out,
+		mux:     http.NewServeMux(),
+		stopped: make(chan struct{}),
+	}
+	s.mux.HandleFunc("/healthz", s.healthz)
+	return s
+}
+
+// healthz reports whether the server accepts callbacks.
+func (s *webhookServer) healthz(w http.ResponseWriter, _ *http.Request) {
+	if s.draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// stream wraps a long-lived streaming handler: the write timeout does not apply, and
+// the stream ends when the server shuts down instead of delaying the shutdown.
+func (s *webhookServer) stream(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.stopped:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// run serves until SIGINT or SIGTERM, then drains the in-flight requests.
+func (s *webhookServer) run() error {
+	flags := s.cmd.Flags()
+	addr, _ := flags.GetString("addr")
+	if addr == "" {
+		port, _ := flags.GetInt("port")
+		addr = fmt.Sprintf(":%d", port)
+	}
+	certFile, _ := flags.GetString("tls-cert")
+	keyFile, _ := flags.GetString("tls-key")
+	readTimeout, _ := flags.GetDuration("read-timeout")
+	writeTimeout, _ := flags.GetDuration("write-timeout")
+	shutdownTimeout, _ := flags.GetDuration("shutdown-timeout")
+
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.logRequests(s.mux),
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readTimeout,
+		WriteTimeout:      writeTimeout,
+	}
+	server.RegisterOnShutdown(func() { close(s.stopped) })
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	scheme := "HTTP"
+	if certFile != "" {
+		scheme = "HTTPS"
+	}
+	s.out.info(fmt.Sprintf("Starting %s server on %s", scheme, addr))
+	s.out.header()
+
+	errc := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errc <- server.ServeTLS(ln, certFile, keyFile)
+		} else {
+			errc <- server.Serve(ln)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	s.out.info("Shutting down, draining the in-flight callbacks")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	s.out.info("Stopped")
+	return nil
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements http.Flusher, for the event streams.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// logRequests logs the requests served by h.
+func (s *webhookServer) logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		s.out.request(r, rec.status, time.Since(start))
+	})
+}
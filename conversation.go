@@ -0,0 +1,252 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Direction of a conversation message.
+type Direction int
+
+// List of conversation message directions.
+const (
+	Outbound Direction = iota
+	Inbound
+)
+
+func (d Direction) String() string {
+	if d == Inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// ConversationMessage represents a message sent to or received from a mobile number.
+type ConversationMessage struct {
+	Direction Direction
+
+	// Normalized mobile number, see NormalizeMobile.
+	Mobile string
+
+	// Batch ID of the send, or of the report carrying the reply.
+	BatchID string
+
+	// Message record no.
+	MessageNo string
+
+	Content string
+	Time    time.Time
+}
+
+// ConversationStore keeps the conversation threads by mobile number.
+type ConversationStore interface {
+	// Append adds a message to the thread of its mobile number.
+	Append(ctx context.Context, message ConversationMessage) error
+
+	// Thread returns the messages of the mobile number from the oldest to the newest.
+	Thread(ctx context.Context, mobile string) ([]ConversationMessage, error)
+}
+
+// InboundMessage represents a reply linked to the message it answers.
+type InboundMessage struct {
+	ConversationMessage
+
+	// Report carrying the reply.
+	Report *ReportMessage
+
+	// InReplyTo is the outbound message the reply answers, nil if it is unknown.
+	InReplyTo *ConversationMessage
+}
+
+// ErrNotReply is returned by Conversations.Receive for reports other than replies.
+var ErrNotReply = errors.New("report is not a reply message")
+
+// Conversations links the replies to the messages they answer and keeps
+// the conversation threads in a ConversationStore.
+//
+// Send the messages through Conversations.Send and plug Conversations.HandleReply
+// into WebhookHandler.OnReply.
+type Conversations struct {
+	client *Client
+	store  ConversationStore
+
+	// OnInbound, if not nil, is called by HandleReply with every received reply.
+	OnInbound func(ctx context.Context, inbound *InboundMessage) error
+}
+
+// NewConversations returns a new Conversations sending through the client.
+// If store is nil, a MemoryConversationStore is used.
+func NewConversations(client *Client, store ConversationStore) *Conversations {
+	if store == nil {
+		store = NewMemoryConversationStore()
+	}
+	return &Conversations{client: client, store: store}
+}
+
+// Store returns the conversation store.
+func (c *Conversations) Store() ConversationStore {
+	return c.store
+}
+
+// Send sends an SMS and appends it to the thread of each destination, except the
+// suppressed ones. The response is returned along with any error.
+func (c *Conversations) Send(ctx context.Context, message Message) (*SendResponse, error) {
+	resp, err := c.client.Send(ctx, message)
+	if err != nil {
+		return resp, err
+	}
+
+	suppressed := make(map[string]bool, len(resp.Suppressed))
+	for _, mobile := range resp.Suppressed {
+		suppressed[mobile] = true
+	}
+
+	now := time.Now()
+	for _, mobile := range SplitDestination(message.Destination) {
+		if suppressed[mobile] {
+			continue
+		}
+		err := c.store.Append(ctx, ConversationMessage{
+			Direction: Outbound,
+			Mobile:    mobile,
+			BatchID:   resp.BatchID,
+			MessageNo: message.MessageNo,
+			Content:   message.Content,
+			Time:      now,
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Receive links the reply to the outbound message it answers and appends it to the thread.
+//
+// The outbound message is matched by BatchID, then by MessageNo, and falls back to
+// the latest message sent to the number.
+func (c *Conversations) Receive(ctx context.Context, report *ReportMessage) (*InboundMessage, error) {
+	if report.StatusCode != StatusReplayContent {
+		return nil, ErrNotReply
+	}
+
+	mobile := NormalizeMobile(report.Destination)
+	thread, err := c.store.Thread(ctx, mobile)
+	if err != nil {
+		return nil, err
+	}
+
+	receivedAt := report.ReportedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	inbound := &InboundMessage{
+		ConversationMessage: ConversationMessage{
+			Direction: Inbound,
+			Mobile:    mobile,
+			BatchID:   report.BatchID,
+			MessageNo: report.MessageNo,
+			Content:   report.ReplyMessage,
+			Time:      receivedAt,
+		},
+		Report:    report,
+		InReplyTo: findOutbound(thread, report.BatchID, report.MessageNo),
+	}
+
+	if err := c.store.Append(ctx, inbound.ConversationMessage); err != nil {
+		return nil, err
+	}
+
+	return inbound, nil
+}
+
+// findOutbound returns the outbound message answered by a reply, nil if there is none.
+func findOutbound(thread []ConversationMessage, batchID, messageNo string) *ConversationMessage {
+	var latest *ConversationMessage
+	var byMessageNo *ConversationMessage
+	for i := len(thread) - 1; i >= 0; i-- {
+		m := &thread[i]
+		if m.Direction != Outbound {
+			continue
+		}
+		if batchID != "" && m.BatchID == batchID {
+			return m
+		}
+		if byMessageNo == nil && messageNo != "" && m.MessageNo == messageNo {
+			byMessageNo = m
+		}
+		if latest == nil {
+			latest = m
+		}
+	}
+	if byMessageNo != nil {
+		return byMessageNo
+	}
+	return latest
+}
+
+// HandleReply receives a reply and passes it to OnInbound. It can be used as
+// WebhookHandler.OnReply.
+func (c *Conversations) HandleReply(ctx context.Context, report *ReportMessage) error {
+	inbound, err := c.Receive(ctx, report)
+	if err != nil {
+		return err
+	}
+	if c.OnInbound != nil {
+		return c.OnInbound(ctx, inbound)
+	}
+	return nil
+}
+
+// Reply sends content back to the number of the inbound message.
+func (c *Conversations) Reply(ctx context.Context, inbound *InboundMessage, content string) (*SendResponse, error) {
+	return c.Send(ctx, Message{
+		Content:     content,
+		Destination: inbound.Mobile,
+	})
+}
+
+// MemoryConversationStore is an in-memory ConversationStore.
+type MemoryConversationStore struct {
+	mu      sync.RWMutex
+	threads map[string][]ConversationMessage
+}
+
+// NewMemoryConversationStore returns a new MemoryConversationStore.
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{threads: make(map[string][]ConversationMessage)}
+}
+
+// Append implements the ConversationStore interface.
+func (s *MemoryConversationStore) Append(_ context.Context, message ConversationMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.threads[message.Mobile] = append(s.threads[message.Mobile], message)
+	return nil
+}
+
+// Thread implements the ConversationStore interface.
+func (s *MemoryConversationStore) Thread(_ context.Context, mobile string) ([]ConversationMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	thread := s.threads[NormalizeMobile(mobile)]
+	return append([]ConversationMessage(nil), thread...), nil
+}
+
+// Mobiles returns the numbers having a thread.
+func (s *MemoryConversationStore) Mobiles() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var mobiles []string
+	for mobile := range s.threads {
+		mobiles = append(mobiles, mobile)
+	}
+	return mobiles
+}
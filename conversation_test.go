@@ -0,0 +1,148 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestConversations(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	batch := 0
+	var sentTo []string
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		r.ParseForm()
+		sentTo = append(sentTo, r.Form.Get("DEST"))
+		batch++
+		fmt.Fprintf(w, "87.00,1,1,0,batch-%d", batch)
+	})
+
+	ctx := context.Background()
+	conversations := NewConversations(client, nil)
+
+	if _, err := conversations.Send(ctx, Message{Content: "Hello", Destination: "0987654321,0922333444"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if _, err := conversations.Send(ctx, Message{Content: "Again", Destination: "0987654321", MessageNo: "002"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+
+	var received []*InboundMessage
+	conversations.OnInbound = func(ctx context.Context, inbound *InboundMessage) error {
+		received = append(received, inbound)
+		return nil
+	}
+
+	tests := []struct {
+		batchID, messageNo string
+		wantContent        string
+	}{
+		{"batch-1", "", "Hello"},
+		{"", "002", "Again"},
+		{"unknown", "", "Again"},
+	}
+	for _, tt := range tests {
+		report := createReportMessage()
+		report.StatusCode = StatusReplayContent
+		report.BatchID = tt.batchID
+		report.MessageNo = tt.messageNo
+
+		if err := conversations.HandleReply(ctx, report); err != nil {
+			t.Fatalf("HandleReply returned unexpected error: %v", err)
+		}
+		inbound := received[len(received)-1]
+		if inbound.InReplyTo == nil || inbound.InReplyTo.Content != tt.wantContent {
+			t.Errorf("Reply to %s/%s linked to %+v, want %q", tt.batchID, tt.messageNo, inbound.InReplyTo, tt.wantContent)
+		}
+	}
+
+	if _, err := conversations.Reply(ctx, received[0], "Thanks"); err != nil {
+		t.Fatalf("Reply returned unexpected error: %v", err)
+	}
+	if got, want := sentTo[len(sentTo)-1], "+886987654321"; got != want {
+		t.Errorf("Reply sent to %v, want %v", got, want)
+	}
+
+	thread, _ := conversations.Store().Thread(ctx, "0987654321")
+	var got []string
+	for _, m := range thread {
+		got = append(got, fmt.Sprintf("%v:%s", m.Direction, m.Content))
+	}
+	want := []string{
+		"outbound:Hello",
+		"outbound:Again",
+		"inbound:Reply, Hello",
+		"inbound:Reply, Hello",
+		"inbound:Reply, Hello",
+		"outbound:Thanks",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Thread returned %v, want %v", got, want)
+	}
+
+	other, _ := conversations.Store().Thread(ctx, "+886922333444")
+	if len(other) != 1 || other[0].BatchID != "batch-1" {
+		t.Errorf("Thread returned %+v, want the first message", other)
+	}
+}
+
+func TestConversations_Send_suppressed(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,1,1,0,batch-1")
+	})
+
+	ctx := context.Background()
+	client.Suppression = NewMemorySuppressionList("0922333444")
+	conversations := NewConversations(client, nil)
+
+	if _, err := conversations.Send(ctx, Message{Content: "Hello", Destination: "0987654321,0922333444"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if thread, _ := conversations.Store().Thread(ctx, "0987654321"); len(thread) != 1 {
+		t.Errorf("Thread returned %+v, want the message", thread)
+	}
+	if thread, _ := conversations.Store().Thread(ctx, "0922333444"); len(thread) != 0 {
+		t.Errorf("Thread returned %+v, want none", thread)
+	}
+
+	resp, err := conversations.Send(ctx, Message{Content: "Hello", Destination: "0922333444"})
+	if err != ErrAllSuppressed {
+		t.Errorf("Send returned error %v, want %v", err, ErrAllSuppressed)
+	}
+	if want := []string{"+886922333444"}; resp == nil || !reflect.DeepEqual(resp.Suppressed, want) {
+		t.Errorf("Send returned %+v, want Suppressed %v", resp, want)
+	}
+}
+
+func TestConversations_Receive_notReply(t *testing.T) {
+	conversations := NewConversations(NewClient("", "", nil), nil)
+	if _, err := conversations.Receive(context.Background(), createReportMessage()); err != ErrNotReply {
+		t.Errorf("Receive returned %v, want %v", err, ErrNotReply)
+	}
+}
+
+func TestConversations_Receive_unknownNumber(t *testing.T) {
+	conversations := NewConversations(NewClient("", "", nil), nil)
+
+	report := createReportMessage()
+	report.StatusCode = StatusReplayContent
+
+	inbound, err := conversations.Receive(context.Background(), report)
+	if err != nil {
+		t.Fatalf("Receive returned unexpected error: %v", err)
+	}
+	if inbound.InReplyTo != nil {
+		t.Errorf("InReplyTo is %+v, want nil", inbound.InReplyTo)
+	}
+	if got, want := inbound.Time, report.ReportedAt; !got.Equal(want) {
+		t.Errorf("Time is %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,434 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventSource is the origin of a TimelineEvent.
+type EventSource int
+
+// List of timeline event sources.
+const (
+	SourceSend EventSource = iota
+	SourceCallback
+	SourcePoll
+)
+
+func (s EventSource) String() string {
+	switch s {
+	case SourceCallback:
+		return "callback"
+	case SourcePoll:
+		return "poll"
+	}
+	return "send"
+}
+
+// SendRecord represents a message sent through the API.
+type SendRecord struct {
+	BatchID   string `json:"batch_id"`
+	MessageNo string `json:"message_no,omitempty"`
+
+	// Normalized mobile numbers the message was sent to, see NormalizeMobile.
+	Recipients []string `json:"recipients"`
+
+	// Hex encoded SHA-256 of the message content, see ContentHash.
+	ContentHash string `json:"content_hash"`
+
+	MMS    bool      `json:"mms,omitempty"`
+	Cost   float64   `json:"cost"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// ContentHash returns the hex encoded SHA-256 of the message content.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewSendRecord returns the record of an SMS sent now.
+func NewSendRecord(message Message, resp *SendResponse) *SendRecord {
+	return newSendRecord(message.Destination, message.MessageNo, message.Content, false, resp)
+}
+
+// NewMMSSendRecord returns the record of an MMS sent now.
+func NewMMSSendRecord(message MMS, resp *SendResponse) *SendRecord {
+	return newSendRecord(message.Destination, message.MessageNo, message.Content, true, resp)
+}
+
+func newSendRecord(destination, messageNo, content string, mms bool, resp *SendResponse) *SendRecord {
+	return &SendRecord{
+		BatchID:     resp.BatchID,
+		MessageNo:   messageNo,
+		Recipients:  SplitDestination(destination),
+		ContentHash: ContentHash(content),
+		MMS:         mms,
+		Cost:        resp.Cost,
+		SentAt:      time.Now(),
+	}
+}
+
+// TimelineEvent represents a status of a recipient.
+type TimelineEvent struct {
+	Source EventSource `json:"source"`
+	Status StatusCode  `json:"status"`
+	Time   time.Time   `json:"time"`
+
+	// Cost of the polled status.
+	Cost float64 `json:"cost,omitempty"`
+
+	// Reply message of a StatusReplayContent callback.
+	Reply string `json:"reply,omitempty"`
+}
+
+// RecipientTimeline is the history of a message sent to a number.
+type RecipientTimeline struct {
+	BatchID   string
+	MessageNo string
+	Mobile    string
+
+	// Send is the record of the send, nil if only reports were received.
+	Send *SendRecord
+
+	// Events from the oldest to the newest.
+	Events []TimelineEvent
+}
+
+// Status returns the latest sending status, ignoring the replies.
+func (t *RecipientTimeline) Status() StatusCode {
+	for i := len(t.Events) - 1; i >= 0; i-- {
+		if t.Events[i].Status != StatusReplayContent {
+			return t.Events[i].Status
+		}
+	}
+	return StatusSent
+}
+
+// Replies returns the reply messages received from the number.
+func (t *RecipientTimeline) Replies() []TimelineEvent {
+	var replies []TimelineEvent
+	for _, event := range t.Events {
+		if event.Status == StatusReplayContent {
+			replies = append(replies, event)
+		}
+	}
+	return replies
+}
+
+// CorrelationStore joins the sends with their callbacks and polled statuses into
+// a timeline per recipient, see Client.Correlation.
+type CorrelationStore interface {
+	// RecordSend records a send.
+	RecordSend(ctx context.Context, record *SendRecord) error
+
+	// RecordReport merges a callback report. It can be used as WebhookHandler.OnDeliveryReport
+	// and WebhookHandler.OnReply.
+	RecordReport(ctx context.Context, report *ReportMessage) error
+
+	// RecordStatus merges a polled delivery status of the batch.
+	RecordStatus(ctx context.Context, batchID string, status DeliveryStatus) error
+
+	// ByBatch returns the timelines of the batch.
+	ByBatch(ctx context.Context, batchID string) ([]*RecipientTimeline, error)
+
+	// ByMobile returns the timelines of the number, from the oldest send.
+	ByMobile(ctx context.Context, mobile string) ([]*RecipientTimeline, error)
+
+	// ByMessageNo returns the timelines of the messages of the record no.
+	ByMessageNo(ctx context.Context, messageNo string) ([]*RecipientTimeline, error)
+}
+
+// RecordDeliveryStatuses merges the polled delivery statuses of the batch into the store.
+func RecordDeliveryStatuses(ctx context.Context, store CorrelationStore, batchID string, statuses []DeliveryStatus) error {
+	for _, status := range statuses {
+		if err := store.RecordStatus(ctx, batchID, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// correlate records the send in the Client.Correlation store.
+func (c *Client) correlate(ctx context.Context, record *SendRecord) error {
+	if c.Correlation == nil {
+		return nil
+	}
+	return c.Correlation.RecordSend(ctx, record)
+}
+
+type timelineKey struct {
+	batchID string
+	mobile  string
+}
+
+// MemoryCorrelationStore is an in-memory CorrelationStore.
+type MemoryCorrelationStore struct {
+	mu        sync.RWMutex
+	timelines map[timelineKey]*RecipientTimeline
+	batches   map[string][]timelineKey
+	mobiles   map[string][]timelineKey
+	messages  map[string][]timelineKey
+}
+
+// NewMemoryCorrelationStore returns a new MemoryCorrelationStore.
+func NewMemoryCorrelationStore() *MemoryCorrelationStore {
+	return &MemoryCorrelationStore{
+		timelines: make(map[timelineKey]*RecipientTimeline),
+		batches:   make(map[string][]timelineKey),
+		mobiles:   make(map[string][]timelineKey),
+		messages:  make(map[string][]timelineKey),
+	}
+}
+
+// timeline returns the timeline of the number in the batch, creating it if needed.
+// The caller must hold the lock.
+func (s *MemoryCorrelationStore) timeline(batchID, mobile, messageNo string) *RecipientTimeline {
+	key := timelineKey{batchID: batchID, mobile: NormalizeMobile(mobile)}
+	t, ok := s.timelines[key]
+	if !ok {
+		t = &RecipientTimeline{BatchID: key.batchID, Mobile: key.mobile}
+		s.timelines[key] = t
+		s.batches[key.batchID] = append(s.batches[key.batchID], key)
+		s.mobiles[key.mobile] = append(s.mobiles[key.mobile], key)
+	}
+	if t.MessageNo == "" && messageNo != "" {
+		t.MessageNo = messageNo
+		s.messages[messageNo] = append(s.messages[messageNo], key)
+	}
+	return t
+}
+
+// RecordSend implements the CorrelationStore interface.
+func (s *MemoryCorrelationStore) RecordSend(_ context.Context, record *SendRecord) error {
+	s.recordSend(record)
+	return nil
+}
+
+// recordSend records the send and reports whether it is new.
+func (s *MemoryCorrelationStore) recordSend(record *SendRecord) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for _, mobile := range record.Recipients {
+		t := s.timeline(record.BatchID, mobile, record.MessageNo)
+		if t.Send != nil {
+			continue
+		}
+		t.Send = record
+		t.Events = insertEvent(t.Events, TimelineEvent{Source: SourceSend, Status: StatusSent, Time: record.SentAt})
+		changed = true
+	}
+	return changed
+}
+
+// RecordReport implements the CorrelationStore interface. A report delivered more
+// than once is recorded once.
+func (s *MemoryCorrelationStore) RecordReport(_ context.Context, report *ReportMessage) error {
+	s.recordReport(report)
+	return nil
+}
+
+// recordReport merges the report and reports whether it is new.
+func (s *MemoryCorrelationStore) recordReport(report *ReportMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at := report.ReportedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	t := s.timeline(report.BatchID, report.Destination, report.MessageNo)
+	event := TimelineEvent{Source: SourceCallback, Status: report.StatusCode, Time: at, Reply: report.ReplyMessage}
+	for _, e := range t.Events {
+		if e.Source == event.Source && e.Status == event.Status && e.Time.Equal(event.Time) && e.Reply == event.Reply {
+			return false
+		}
+	}
+	t.Events = insertEvent(t.Events, event)
+	return true
+}
+
+// RecordStatus implements the CorrelationStore interface. A status equal to the
+// previously polled one is not recorded again.
+func (s *MemoryCorrelationStore) RecordStatus(_ context.Context, batchID string, status DeliveryStatus) error {
+	s.recordStatus(batchID, status, time.Now())
+	return nil
+}
+
+// recordStatus merges the status polled at the time and reports whether it changed.
+func (s *MemoryCorrelationStore) recordStatus(batchID string, status DeliveryStatus, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.timeline(batchID, status.Mobile, "")
+	for i := len(t.Events) - 1; i >= 0; i-- {
+		if t.Events[i].Source == SourcePoll {
+			if t.Events[i].Status == status.Status {
+				return false
+			}
+			break
+		}
+	}
+	t.Events = insertEvent(t.Events, TimelineEvent{Source: SourcePoll, Status: status.Status, Time: at, Cost: status.Cost})
+	return true
+}
+
+// insertEvent inserts the event in time order, after the events of the same time.
+func insertEvent(events []TimelineEvent, event TimelineEvent) []TimelineEvent {
+	i := sort.Search(len(events), func(i int) bool { return events[i].Time.After(event.Time) })
+	events = append(events, TimelineEvent{})
+	copy(events[i+1:], events[i:])
+	events[i] = event
+	return events
+}
+
+// ByBatch implements the CorrelationStore interface.
+func (s *MemoryCorrelationStore) ByBatch(_ context.Context, batchID string) ([]*RecipientTimeline, error) {
+	return s.lookup(s.batches, batchID), nil
+}
+
+// ByMobile implements the CorrelationStore interface.
+func (s *MemoryCorrelationStore) ByMobile(_ context.Context, mobile string) ([]*RecipientTimeline, error) {
+	return s.lookup(s.mobiles, NormalizeMobile(mobile)), nil
+}
+
+// ByMessageNo implements the CorrelationStore interface.
+func (s *MemoryCorrelationStore) ByMessageNo(_ context.Context, messageNo string) ([]*RecipientTimeline, error) {
+	return s.lookup(s.messages, messageNo), nil
+}
+
+// lookup returns copies of the timelines of the index entry.
+func (s *MemoryCorrelationStore) lookup(index map[string][]timelineKey, value string) []*RecipientTimeline {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := index[value]
+	timelines := make([]*RecipientTimeline, 0, len(keys))
+	for _, key := range keys {
+		t := *s.timelines[key]
+		t.Events = append([]TimelineEvent(nil), t.Events...)
+		timelines = append(timelines, &t)
+	}
+	return timelines
+}
+
+// correlationRecord is a line of the FileCorrelationStore.
+type correlationRecord struct {
+	Send    *SendRecord     `json:"send,omitempty"`
+	Report  *ReportMessage  `json:"report,omitempty"`
+	BatchID string          `json:"batch_id,omitempty"`
+	Status  *DeliveryStatus `json:"status,omitempty"`
+	At      time.Time       `json:"at"`
+}
+
+// FileCorrelationStore is a CorrelationStore persisted to an append-only JSON Lines file,
+// loaded in memory when opened.
+type FileCorrelationStore struct {
+	*MemoryCorrelationStore
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileCorrelationStore opens the store at path, creating it if needed.
+func OpenFileCorrelationStore(path string) (*FileCorrelationStore, error) {
+	s := &FileCorrelationStore{MemoryCorrelationStore: NewMemoryCorrelationStore()}
+
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileCorrelationStore) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		record := new(correlationRecord)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		switch {
+		case record.Send != nil:
+			s.recordSend(record.Send)
+		case record.Report != nil:
+			s.recordReport(record.Report)
+		case record.Status != nil:
+			s.recordStatus(record.BatchID, *record.Status, record.At)
+		}
+	}
+	return scanner.Err()
+}
+
+// append writes the record to the file.
+func (s *FileCorrelationStore) append(record *correlationRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// RecordSend implements the CorrelationStore interface.
+func (s *FileCorrelationStore) RecordSend(_ context.Context, record *SendRecord) error {
+	if !s.recordSend(record) {
+		return nil
+	}
+	return s.append(&correlationRecord{Send: record, At: record.SentAt})
+}
+
+// RecordReport implements the CorrelationStore interface.
+func (s *FileCorrelationStore) RecordReport(_ context.Context, report *ReportMessage) error {
+	if !s.recordReport(report) {
+		return nil
+	}
+	return s.append(&correlationRecord{Report: report, At: time.Now()})
+}
+
+// RecordStatus implements the CorrelationStore interface.
+func (s *FileCorrelationStore) RecordStatus(_ context.Context, batchID string, status DeliveryStatus) error {
+	at := time.Now()
+	if !s.recordStatus(batchID, status, at) {
+		return nil
+	}
+	return s.append(&correlationRecord{BatchID: batchID, Status: &status, At: at})
+}
+
+// Close closes the file.
+func (s *FileCorrelationStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
@@ -0,0 +1,201 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func timelineStatuses(timeline *RecipientTimeline) []string {
+	var statuses []string
+	for _, event := range timeline.Events {
+		statuses = append(statuses, fmt.Sprintf("%v:%d", event.Source, event.Status))
+	}
+	return statuses
+}
+
+func recordCorrelations(t *testing.T, store CorrelationStore) {
+	ctx := context.Background()
+	sentAt := time.Date(2009, 2, 10, 11, 59, 0, 0, taipei)
+
+	err := store.RecordSend(ctx, &SendRecord{
+		BatchID:     "b1",
+		MessageNo:   "001",
+		Recipients:  []string{"+886987654321", "+886912345678"},
+		ContentHash: ContentHash("Hello"),
+		SentAt:      sentAt,
+	})
+	if err != nil {
+		t.Fatalf("RecordSend returned unexpected error: %v", err)
+	}
+
+	report := NewDeliveryReport("b1", "0987654321", StatusMessageReceived)
+	report.ReportedAt = sentAt.Add(time.Minute)
+	reply := NewReplyReport("b1", "+886987654321", "YES")
+	reply.ReportedAt = sentAt.Add(2 * time.Minute)
+	for _, r := range []*ReportMessage{report, report, reply} {
+		if err := store.RecordReport(ctx, r); err != nil {
+			t.Fatalf("RecordReport returned unexpected error: %v", err)
+		}
+	}
+
+	statuses := []DeliveryStatus{
+		{Mobile: "+886912345678", Status: StatusSent},
+		{Mobile: "+886912345678", Status: StatusSent},
+		{Mobile: "+886912345678", Status: StatusMobileNumberNotExist},
+	}
+	if err := RecordDeliveryStatuses(ctx, store, "b1", statuses); err != nil {
+		t.Fatalf("RecordDeliveryStatuses returned unexpected error: %v", err)
+	}
+}
+
+func testCorrelations(t *testing.T, store CorrelationStore) {
+	ctx := context.Background()
+
+	timelines, _ := store.ByBatch(ctx, "b1")
+	if len(timelines) != 2 {
+		t.Fatalf("ByBatch returned %d timelines, want 2", len(timelines))
+	}
+
+	timelines, _ = store.ByMobile(ctx, "0987654321")
+	if len(timelines) != 1 {
+		t.Fatalf("ByMobile returned %d timelines, want 1", len(timelines))
+	}
+	received := timelines[0]
+	if got, want := timelineStatuses(received), []string{"send:0", "callback:100", "callback:999"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Timeline is %v, want %v", got, want)
+	}
+	if got, want := received.Status(), StatusMessageReceived; got != want {
+		t.Errorf("Status returned %v, want %v", got, want)
+	}
+	if replies := received.Replies(); len(replies) != 1 || replies[0].Reply != "YES" {
+		t.Errorf("Replies returned %+v, want YES", replies)
+	}
+	if received.Send == nil || received.Send.ContentHash != ContentHash("Hello") {
+		t.Errorf("Send is %+v, want the send record", received.Send)
+	}
+
+	timelines, _ = store.ByMessageNo(ctx, "001")
+	if len(timelines) != 2 {
+		t.Fatalf("ByMessageNo returned %d timelines, want 2", len(timelines))
+	}
+	failed := timelines[1]
+	if got, want := timelineStatuses(failed), []string{"send:0", "poll:0", "poll:103"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Timeline is %v, want %v", got, want)
+	}
+	if got, want := failed.Status(), StatusMobileNumberNotExist; got != want {
+		t.Errorf("Status returned %v, want %v", got, want)
+	}
+
+	if timelines, _ := store.ByBatch(ctx, "unknown"); len(timelines) != 0 {
+		t.Errorf("ByBatch returned %v, want none", timelines)
+	}
+}
+
+func TestMemoryCorrelationStore(t *testing.T) {
+	store := NewMemoryCorrelationStore()
+	recordCorrelations(t, store)
+	testCorrelations(t, store)
+}
+
+func TestFileCorrelationStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "correlation.jsonl")
+
+	store, err := OpenFileCorrelationStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileCorrelationStore returned unexpected error: %v", err)
+	}
+	recordCorrelations(t, store)
+	store.Close()
+
+	store, err = OpenFileCorrelationStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileCorrelationStore returned unexpected error: %v", err)
+	}
+	defer store.Close()
+	testCorrelations(t, store)
+}
+
+func TestMemoryCorrelationStore_sameSuffix(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCorrelationStore()
+
+	// The numbers sharing the digits shown by a masker keep their own timelines.
+	store.RecordSend(ctx, &SendRecord{BatchID: "b1", Recipients: []string{"+886912345678", "+886987655678"}})
+	if timelines, _ := store.ByBatch(ctx, "b1"); len(timelines) != 2 {
+		t.Errorf("ByBatch returned %d timelines, want 2", len(timelines))
+	}
+	if timelines, _ := store.ByMobile(ctx, "0911115678"); len(timelines) != 0 {
+		t.Errorf("ByMobile returned %d timelines, want none", len(timelines))
+	}
+}
+
+func TestClient_Send_correlation(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,2,2,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	client.Correlation = NewMemoryCorrelationStore()
+	_, err := client.Send(context.Background(), Message{Content: "Hello", Destination: "0987654321,0912345678", MessageNo: "001"})
+	if err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+
+	timelines, _ := client.Correlation.ByBatch(context.Background(), "00000000-0000-0000-0000-000000000000")
+	if len(timelines) != 2 {
+		t.Fatalf("ByBatch returned %d timelines, want 2", len(timelines))
+	}
+	if got := timelines[0]; got.Mobile != "+886987654321" || got.MessageNo != "001" || got.Send.Cost != 2 {
+		t.Errorf("Timeline is %+v", got)
+	}
+}
+
+// failingCorrelationStore fails to record the sends.
+type failingCorrelationStore struct {
+	*MemoryCorrelationStore
+}
+
+func (failingCorrelationStore) RecordSend(context.Context, *SendRecord) error {
+	return errors.New("failure")
+}
+
+func TestClient_Send_correlationError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	ledger := NewMemoryLedger()
+	client.Ledger = ledger
+	client.Correlation = failingCorrelationStore{NewMemoryCorrelationStore()}
+	var recordErr error
+	client.OnRecordError = func(ctx context.Context, resp *SendResponse, err error) {
+		recordErr = err
+	}
+
+	// The sent message is not reported as failed, and its cost is recorded even if
+	// it fails to be correlated.
+	resp, err := client.Send(context.Background(), Message{Content: "Hello", Destination: "0987654321"})
+	if err != nil {
+		t.Errorf("Send returned unexpected error: %v", err)
+	}
+	if resp == nil || resp.BatchID != "00000000-0000-0000-0000-000000000000" {
+		t.Errorf("Send returned %+v, want the response", resp)
+	}
+	if recordErr == nil || recordErr.Error() != "failure" {
+		t.Errorf("OnRecordError received %v, want failure", recordErr)
+	}
+	if entries, _ := ledger.Entries(context.Background()); len(entries) != 1 {
+		t.Errorf("Entries returned %d entries, want 1", len(entries))
+	}
+}
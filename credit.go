@@ -32,6 +32,9 @@ func (c *Client) GetCredit(ctx context.Context) (float64, error) {
 	if err != nil {
 		return 0.0, err
 	}
+	if c.Metrics != nil {
+		c.Metrics.ObserveCredit(*credit)
+	}
 
 	return *credit, nil
 }
@@ -0,0 +1,160 @@
+package every8d
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultCreditMonitorInterval = 5 * time.Minute
+
+// CreditAlert represents the credit crossing a threshold.
+type CreditAlert struct {
+	// Threshold crossed.
+	Threshold float64
+
+	// Credit is the balance that crossed the threshold.
+	Credit float64
+
+	// Low is true when the credit fell below the threshold, and false when it recovered.
+	Low bool
+}
+
+// CreditMonitor keeps track of the account credit and alerts when it crosses the
+// thresholds. It polls GetCredit with Run, and is updated by the sends of the client
+// whose Client.CreditMonitor it is:
+//
+//	monitor := client.NewCreditMonitor(time.Minute, 1000, 100)
+//	monitor.OnAlert = func(alert every8d.CreditAlert) { ... }
+//	client.CreditMonitor = monitor
+//	go monitor.Run(ctx)
+type CreditMonitor struct {
+	// Interval between polls. Defaults to 5 minutes.
+	Interval time.Duration
+
+	// Hysteresis is the margin above a threshold the credit must reach to recover from
+	// it, so a balance hovering around the threshold does not alert repeatedly.
+	Hysteresis float64
+
+	// OnAlert, if not nil, is called when the credit falls below a threshold, or
+	// recovers above it plus the hysteresis.
+	OnAlert func(alert CreditAlert)
+
+	// OnError, if not nil, is called when polling the credit fails.
+	OnError func(err error)
+
+	client     *Client
+	thresholds []float64
+
+	mu        sync.Mutex
+	credit    float64
+	updatedAt time.Time
+	low       map[float64]bool
+}
+
+// NewCreditMonitor returns a new CreditMonitor polling on the given interval, alerting
+// at the thresholds.
+func (c *Client) NewCreditMonitor(interval time.Duration, thresholds ...float64) *CreditMonitor {
+	thresholds = append([]float64(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(thresholds)))
+
+	return &CreditMonitor{
+		Interval:   interval,
+		client:     c,
+		thresholds: thresholds,
+		low:        make(map[float64]bool),
+	}
+}
+
+// Credit returns the last known credit and the time it was updated, zero if it never was.
+func (m *CreditMonitor) Credit() (float64, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.credit, m.updatedAt
+}
+
+// Low returns the thresholds the credit is below, from the highest.
+func (m *CreditMonitor) Low() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var low []float64
+	for _, threshold := range m.thresholds {
+		if m.low[threshold] {
+			low = append(low, threshold)
+		}
+	}
+	return low
+}
+
+// Update sets the credit, and calls OnAlert for each threshold it crossed.
+// Negative credits are ignored.
+func (m *CreditMonitor) Update(credit float64) {
+	if credit < 0 {
+		return
+	}
+
+	m.mu.Lock()
+	m.credit, m.updatedAt = credit, time.Now()
+
+	var alerts []CreditAlert
+	for _, threshold := range m.thresholds {
+		switch low := m.low[threshold]; {
+		case !low && credit < threshold:
+			m.low[threshold] = true
+			alerts = append(alerts, CreditAlert{Threshold: threshold, Credit: credit, Low: true})
+		case low && credit >= threshold+m.Hysteresis:
+			m.low[threshold] = false
+			alerts = append(alerts, CreditAlert{Threshold: threshold, Credit: credit})
+		}
+	}
+	m.mu.Unlock()
+
+	if m.OnAlert != nil {
+		for _, alert := range alerts {
+			m.OnAlert(alert)
+		}
+	}
+}
+
+// Poll retrieves the credit once and updates the monitor.
+func (m *CreditMonitor) Poll(ctx context.Context) (float64, error) {
+	credit, err := m.client.GetCredit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	m.Update(credit)
+	return credit, nil
+}
+
+// Run polls the credit until ctx is canceled, then returns ctx.Err().
+func (m *CreditMonitor) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultCreditMonitorInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Poll(ctx); err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if m.OnError != nil {
+				m.OnError(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreditMonitor_Update(t *testing.T) {
+	client := NewClient("UID", "PWD", nil)
+	monitor := client.NewCreditMonitor(0, 100, 500)
+	monitor.Hysteresis = 10
+
+	var got []CreditAlert
+	monitor.OnAlert = func(alert CreditAlert) {
+		got = append(got, alert)
+	}
+
+	for _, credit := range []float64{600, 499, 505, 499, 515, 90, -1, 95, 600} {
+		monitor.Update(credit)
+	}
+
+	want := []CreditAlert{
+		{Threshold: 500, Credit: 499, Low: true},
+		{Threshold: 500, Credit: 515},
+		{Threshold: 500, Credit: 90, Low: true},
+		{Threshold: 100, Credit: 90, Low: true},
+		{Threshold: 500, Credit: 600},
+		{Threshold: 100, Credit: 600},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OnAlert received %+v, want %+v", got, want)
+	}
+	if credit, _ := monitor.Credit(); credit != 600 {
+		t.Errorf("Credit returned %v, want %v", credit, 600.0)
+	}
+	if low := monitor.Low(); len(low) != 0 {
+		t.Errorf("Low returned %v, want none", low)
+	}
+}
+
+func TestCreditMonitor_Run(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var polls int32
+	mux.HandleFunc("/API21/HTTP/getCredit.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if atomic.AddInt32(&polls, 1) == 1 {
+			fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+			return
+		}
+		fmt.Fprint(w, "80")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	alerts := make(chan CreditAlert, 1)
+	monitor := client.NewCreditMonitor(time.Millisecond, 100)
+	monitor.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	monitor.OnAlert = func(alert CreditAlert) {
+		alerts <- alert
+	}
+
+	done := make(chan error)
+	go func() { done <- monitor.Run(ctx) }()
+
+	if err := <-errs; err == nil {
+		t.Error("OnError received nil, want an error")
+	}
+	if got, want := <-alerts, (CreditAlert{Threshold: 100, Credit: 80, Low: true}); got != want {
+		t.Errorf("OnAlert received %+v, want %+v", got, want)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClient_Send_creditMonitor(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	client.CreditMonitor = client.NewCreditMonitor(0, 100)
+
+	if _, err := client.Send(context.Background(), Message{Content: "Hello", Destination: "0987654321"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if credit, at := client.CreditMonitor.Credit(); credit != 87 || at.IsZero() {
+		t.Errorf("Credit returned %v at %v, want %v", credit, at, 87.0)
+	}
+	if got, want := client.CreditMonitor.Low(), []float64{100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Low returned %v, want %v", got, want)
+	}
+}
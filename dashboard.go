@@ -0,0 +1,259 @@
+package every8d
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDashboardCapacity  = 500
+	defaultDashboardCreditTTL = time.Minute
+)
+
+//go:embed templates/dashboard.html
+var dashboardFS embed.FS
+
+var dashboardTemplate = template.Must(template.ParseFS(dashboardFS, "templates/dashboard.html"))
+
+// Dashboard is an HTML page showing the recent callbacks, the count of each status
+// and the account credit, with a search by phone number or BatchID. Feed it with
+// Record as a ReportHandlerFunc, and serve it behind an authentication:
+//
+//	dashboard := every8d.NewDashboard(client, 0)
+//	dashboard.Masker = every8d.DefaultMasker
+//	http.Handle("/callback", &every8d.WebhookHandler{OnDeliveryReport: dashboard.Record, OnReply: dashboard.Record})
+//	http.Handle("/dashboard/", auth.Handler(dashboard))
+//
+// The query parameters of the page other than the search, e.g. the token of a
+// WebhookAuthenticator, are kept by the search form and the EventsURL.
+type Dashboard struct {
+	client   *Client
+	capacity int
+
+	// Language of the status text. Defaults to DefaultLanguage.
+	Lang string
+
+	// EventsURL, if not empty, is the EventStream URL the page listens to, reloading
+	// when a report arrives.
+	EventsURL string
+
+	// CreditTTL is the time the credit is cached. Defaults to 1 minute.
+	CreditTTL time.Duration
+
+	// Masker, if not nil, masks the phone numbers of the recorded reports. The
+	// search by phone number then matches the masked number.
+	Masker *Masker
+
+	mu       sync.Mutex
+	reports  []*ReportMessage
+	times    []time.Time
+	next     int
+	counts   map[StatusCode]int
+	credit   float64
+	creditAt time.Time
+}
+
+// NewDashboard returns a new Dashboard keeping the last capacity reports, 500 if
+// capacity is not positive. The credit is retrieved through the client, if not nil.
+func NewDashboard(client *Client, capacity int) *Dashboard {
+	if capacity <= 0 {
+		capacity = defaultDashboardCapacity
+	}
+	return &Dashboard{
+		client:   client,
+		capacity: capacity,
+		counts:   make(map[StatusCode]int),
+	}
+}
+
+// Record adds the report to the dashboard. It can be used as a ReportHandlerFunc.
+func (d *Dashboard) Record(_ context.Context, report *ReportMessage) error {
+	report = d.Masker.MaskReport(report)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.reports) < d.capacity {
+		d.reports = append(d.reports, report)
+		d.times = append(d.times, time.Now())
+	} else {
+		d.reports[d.next] = report
+		d.times[d.next] = time.Now()
+	}
+	d.next = (d.next + 1) % d.capacity
+	d.counts[report.StatusCode]++
+	return nil
+}
+
+type dashboardReport struct {
+	*ReportMessage
+	Time     string
+	Status   StatusCode
+	Text     string
+	Category string
+}
+
+type dashboardCount struct {
+	Status   StatusCode
+	Text     string
+	Category string
+	Count    int
+}
+
+type dashboardPage struct {
+	Lang      string
+	EventsURL string
+	Query     string
+	Params    url.Values
+	ClearURL  string
+	Credit    float64
+	CreditErr error
+	Total     int
+	Counts    []dashboardCount
+	Reports   []dashboardReport
+}
+
+// Search returns the recent reports, from the newest, whose BatchID or phone number
+// contains the query. All recent reports are returned if the query is empty.
+func (d *Dashboard) Search(query string) []*ReportMessage {
+	reports, _ := d.search(query)
+	return reports
+}
+
+func (d *Dashboard) search(query string) ([]*ReportMessage, []time.Time) {
+	query = strings.TrimSpace(query)
+	mobile := NormalizeMobile(query)
+	masked := d.Masker != nil && d.Masker.Policy != MaskNone
+	if masked {
+		mobile = d.Masker.Mask(query)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var reports []*ReportMessage
+	var times []time.Time
+	for i := 1; i <= len(d.reports); i++ {
+		j := (d.next - i + len(d.reports)) % len(d.reports)
+		report := d.reports[j]
+		if query != "" && !strings.Contains(report.BatchID, query) {
+			if masked && report.Destination != mobile ||
+				!masked && !strings.Contains(NormalizeMobile(report.Destination), mobile) {
+				continue
+			}
+		}
+		reports = append(reports, report)
+		times = append(times, d.times[j])
+	}
+	return reports, times
+}
+
+// Counts returns the number of reports received for each status code.
+func (d *Dashboard) Counts() map[StatusCode]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	counts := make(map[StatusCode]int, len(d.counts))
+	for code, n := range d.counts {
+		counts[code] = n
+	}
+	return counts
+}
+
+// getCredit returns the credit, cached for CreditTTL.
+func (d *Dashboard) getCredit(ctx context.Context) (float64, error) {
+	ttl := d.CreditTTL
+	if ttl <= 0 {
+		ttl = defaultDashboardCreditTTL
+	}
+
+	d.mu.Lock()
+	if !d.creditAt.IsZero() && time.Since(d.creditAt) < ttl {
+		defer d.mu.Unlock()
+		return d.credit, nil
+	}
+	d.mu.Unlock()
+
+	credit, err := d.client.GetCredit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	d.credit, d.creditAt = credit, time.Now()
+	d.mu.Unlock()
+	return credit, nil
+}
+
+// ServeHTTP implements the http.Handler interface. The "q" query parameter searches
+// the reports by phone number or BatchID.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	lang := d.Lang
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
+	params := r.URL.Query()
+	page := &dashboardPage{
+		Lang:      lang,
+		EventsURL: d.EventsURL,
+		Query:     params.Get("q"),
+		ClearURL:  "?",
+	}
+	params.Del("q")
+	if len(params) > 0 {
+		page.Params = params
+		page.ClearURL += params.Encode()
+		if page.EventsURL != "" {
+			sep := "?"
+			if strings.Contains(page.EventsURL, "?") {
+				sep = "&"
+			}
+			page.EventsURL += sep + params.Encode()
+		}
+	}
+	if d.client != nil {
+		page.Credit, page.CreditErr = d.getCredit(r.Context())
+	}
+
+	counts := d.Counts()
+	for code, n := range counts {
+		page.Total += n
+		page.Counts = append(page.Counts, dashboardCount{
+			Status:   code,
+			Text:     code.TextIn(lang),
+			Category: code.Category().String(),
+			Count:    n,
+		})
+	}
+	sort.Slice(page.Counts, func(i, j int) bool { return page.Counts[i].Status < page.Counts[j].Status })
+
+	reports, times := d.search(page.Query)
+	for i, report := range reports {
+		page.Reports = append(page.Reports, dashboardReport{
+			ReportMessage: report,
+			Time:          times[i].Format("2006/01/02 15:04:05"),
+			Status:        report.StatusCode,
+			Text:          report.StatusCode.TextIn(lang),
+			Category:      report.StatusCode.Category().String(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
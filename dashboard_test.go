@@ -0,0 +1,106 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDashboard_Search(t *testing.T) {
+	dashboard := NewDashboard(nil, 3)
+	for i, mobile := range []string{"0911111111", "0922222222", "0933333333", "0911111111"} {
+		dashboard.Record(context.Background(), NewDeliveryReport(fmt.Sprintf("batch-%d", i), mobile, StatusMessageReceived))
+	}
+
+	batchIDs := func(reports []*ReportMessage) []string {
+		var ids []string
+		for _, report := range reports {
+			ids = append(ids, report.BatchID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"batch-3", "batch-2", "batch-1"}},
+		{"0911111111", []string{"batch-3"}},
+		{"+886922222222", []string{"batch-1"}},
+		{"batch-2", []string{"batch-2"}},
+		{"batch-0", nil},
+	}
+	for _, tt := range tests {
+		if got := batchIDs(dashboard.Search(tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) returned %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if got, want := dashboard.Counts(), map[StatusCode]int{StatusMessageReceived: 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts returned %v, want %v", got, want)
+	}
+}
+
+func TestDashboard_ServeHTTP(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/API21/HTTP/getCredit.ashx", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, "79")
+	})
+
+	dashboard := NewDashboard(client, 0)
+	dashboard.EventsURL = "/events"
+
+	ctx := context.Background()
+	dashboard.Record(ctx, NewDeliveryReport("batch-1", "0987654321", StatusMessageReceived))
+	dashboard.Record(ctx, NewReplyReport("batch-1", "0987654321", "<b>YES</b>"))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		dashboard.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?q=0987654321", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("ServeHTTP returned status %d, want %d", w.Code, http.StatusOK)
+		}
+		body := w.Body.String()
+		for _, want := range []string{"79.00", "發送成功", StatusReplayContent.Text(), "&lt;b&gt;YES&lt;/b&gt;", `value="0987654321"`, `new EventSource("/events")`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Dashboard does not contain %q", want)
+			}
+		}
+	}
+	if calls != 1 {
+		t.Errorf("GetCredit called %d times, want 1", calls)
+	}
+}
+
+func TestDashboard_masker(t *testing.T) {
+	dashboard := NewDashboard(nil, 0)
+	dashboard.Masker = &Masker{Policy: MaskPartial}
+	dashboard.EventsURL = "/events"
+	dashboard.Record(context.Background(), NewDeliveryReport("batch-1", "0987654321", StatusMessageReceived))
+	dashboard.Record(context.Background(), NewDeliveryReport("batch-2", "0912345678", StatusMessageReceived))
+
+	if got := dashboard.Search("0987654321"); len(got) != 1 || got[0].BatchID != "batch-1" {
+		t.Errorf("Search returned %v, want batch-1", got)
+	}
+
+	w := httptest.NewRecorder()
+	dashboard.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard/?q=batch&token=secret", nil))
+	body := w.Body.String()
+	if strings.Contains(body, "987654321") {
+		t.Error("Dashboard contains the phone number unmasked")
+	}
+	for _, want := range []string{"8869****4321", `name="token" value="secret"`, `new EventSource("/events?token=secret")`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Dashboard does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package every8d
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDedupWindow   = 24 * time.Hour
+	defaultDedupCapacity = 10000
+)
+
+// Key returns the identity of the report, made of the BatchID, RM, STATUS, RT and MR fields.
+// EVERY8D may deliver the same report more than once, the copies share the same key.
+func (m *ReportMessage) Key() string {
+	h := sha256.New()
+	for _, field := range []string{m.BatchID, m.Destination, strconv.Itoa(int(m.StatusCode)), m.ReportTime, m.MessageNo} {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DedupStore records the keys of the reports already handled.
+type DedupStore interface {
+	// Seen reports whether the key was marked and has not expired.
+	Seen(key string) (bool, error)
+
+	// Mark records the key until expiresAt.
+	Mark(key string, expiresAt time.Time) error
+
+	// SeenOrMark atomically reports whether the key was marked and has not expired,
+	// and marks it until expiresAt if not.
+	SeenOrMark(key string, expiresAt time.Time) (bool, error)
+
+	// Unmark forgets the key.
+	Unmark(key string) error
+}
+
+// Deduplicator detects the reports delivered more than once within a window.
+type Deduplicator struct {
+	// Store of the handled reports. Defaults to a MemoryDedupStore of 10000 entries.
+	Store DedupStore
+
+	// Window during which a repeated report is a duplicate. Defaults to 24 hours.
+	Window time.Duration
+
+	// DeliverDuplicates passes the duplicates to the handlers, which can tell them
+	// apart with IsDuplicate. Otherwise duplicates are acknowledged without being handled.
+	DeliverDuplicates bool
+
+	once sync.Once
+}
+
+func (d *Deduplicator) init() {
+	d.once.Do(func() {
+		if d.Store == nil {
+			d.Store = NewMemoryDedupStore(defaultDedupCapacity)
+		}
+		if d.Window <= 0 {
+			d.Window = defaultDedupWindow
+		}
+	})
+}
+
+// Seen reports whether the report was already handled within the window.
+func (d *Deduplicator) Seen(report *ReportMessage) (bool, error) {
+	d.init()
+	return d.Store.Seen(report.Key())
+}
+
+// Mark records the report as handled.
+func (d *Deduplicator) Mark(report *ReportMessage) error {
+	d.init()
+	return d.Store.Mark(report.Key(), time.Now().Add(d.Window))
+}
+
+// Claim atomically reports whether the report was already handled within the window,
+// and records it as handled if not. Of concurrent copies of a report, only one claims it.
+func (d *Deduplicator) Claim(report *ReportMessage) (bool, error) {
+	d.init()
+	return d.Store.SeenOrMark(report.Key(), time.Now().Add(d.Window))
+}
+
+// Release forgets a report claimed but not handled, so its retries are not duplicates.
+func (d *Deduplicator) Release(report *ReportMessage) error {
+	d.init()
+	return d.Store.Unmark(report.Key())
+}
+
+type duplicateKey struct{}
+
+// withDuplicate returns a copy of ctx flagging the report as a duplicate.
+func withDuplicate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, duplicateKey{}, true)
+}
+
+// IsDuplicate reports whether the report passed to a ReportHandlerFunc along with ctx
+// was already handled, see Deduplicator.
+func IsDuplicate(ctx context.Context) bool {
+	duplicate, _ := ctx.Value(duplicateKey{}).(bool)
+	return duplicate
+}
+
+// MemoryDedupStore is an in-memory DedupStore evicting the least recently marked keys.
+type MemoryDedupStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type dedupEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewMemoryDedupStore returns a new MemoryDedupStore holding up to capacity keys.
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	if capacity <= 0 {
+		capacity = defaultDedupCapacity
+	}
+	return &MemoryDedupStore{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Seen implements the DedupStore interface.
+func (s *MemoryDedupStore) Seen(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seen(key), nil
+}
+
+// Mark implements the DedupStore interface.
+func (s *MemoryDedupStore) Mark(key string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mark(key, expiresAt)
+	return nil
+}
+
+// SeenOrMark implements the DedupStore interface.
+func (s *MemoryDedupStore) SeenOrMark(key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen(key) {
+		return true, nil
+	}
+	s.mark(key, expiresAt)
+	return false, nil
+}
+
+// Unmark implements the DedupStore interface.
+func (s *MemoryDedupStore) Unmark(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		s.order.Remove(e)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryDedupStore) seen(key string) bool {
+	e, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(e.Value.(*dedupEntry).expiresAt) {
+		s.order.Remove(e)
+		delete(s.entries, key)
+		return false
+	}
+	return true
+}
+
+func (s *MemoryDedupStore) mark(key string, expiresAt time.Time) {
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*dedupEntry).expiresAt = expiresAt
+		s.order.MoveToFront(e)
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&dedupEntry{key: key, expiresAt: expiresAt})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*dedupEntry).key)
+	}
+}
+
+// Len returns the number of keys held.
+func (s *MemoryDedupStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+// liveEntries returns the live entries from the oldest to the newest.
+func (s *MemoryDedupStore) liveEntries() []dedupEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var entries []dedupEntry
+	for e := s.order.Back(); e != nil; e = e.Prev() {
+		if entry := e.Value.(*dedupEntry); now.Before(entry.expiresAt) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// FileDedupStore is a DedupStore persisting the keys to a file, so duplicates are
+// still detected after a restart. The keys are held in memory as a MemoryDedupStore.
+//
+// The file is compacted to the live keys when opened, and whenever it has grown to
+// twice the capacity of lines since the last compaction.
+type FileDedupStore struct {
+	*MemoryDedupStore
+
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	lines int
+}
+
+// OpenFileDedupStore opens the store at path, creating it if needed, and loads the keys
+// that have not expired.
+func OpenFileDedupStore(path string, capacity int) (*FileDedupStore, error) {
+	store := &FileDedupStore{MemoryDedupStore: NewMemoryDedupStore(capacity), path: path}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			expires, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			store.MemoryDedupStore.Mark(fields[1], time.Unix(0, expires))
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// compact rewrites the file with the live entries, then appends to the compacted file.
+func (s *FileDedupStore) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	entries := s.liveEntries()
+	w := bufio.NewWriter(f)
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d %s\n", entry.expiresAt.UnixNano(), entry.key)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+		return err
+	}
+	s.lines = len(entries)
+	return nil
+}
+
+// write appends the line of the key to the file, compacting it when it has grown too long.
+func (s *FileDedupStore) write(key string, expiresAt time.Time) error {
+	if _, err := fmt.Fprintf(s.file, "%d %s\n", expiresAt.UnixNano(), key); err != nil {
+		return err
+	}
+	if s.lines++; s.lines >= 2*s.capacity {
+		return s.compact()
+	}
+	return nil
+}
+
+// Mark implements the DedupStore interface.
+func (s *FileDedupStore) Mark(key string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.MemoryDedupStore.Mark(key, expiresAt)
+	return s.write(key, expiresAt)
+}
+
+// SeenOrMark implements the DedupStore interface.
+func (s *FileDedupStore) SeenOrMark(key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seen, _ := s.MemoryDedupStore.SeenOrMark(key, expiresAt); seen {
+		return true, nil
+	}
+	return false, s.write(key, expiresAt)
+}
+
+// Unmark implements the DedupStore interface.
+func (s *FileDedupStore) Unmark(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.MemoryDedupStore.Unmark(key)
+	return s.write(key, time.Unix(0, 0))
+}
+
+// Close closes the file.
+func (s *FileDedupStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
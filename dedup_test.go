@@ -0,0 +1,192 @@
+package every8d
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReportMessage_Key(t *testing.T) {
+	a := createReportMessage()
+	b := createReportMessage()
+	b.ReplyMessage = "Other"
+	if a.Key() != b.Key() {
+		t.Errorf("Key of reports differing only by SM should be equal")
+	}
+
+	for _, modify := range []func(m *ReportMessage){
+		func(m *ReportMessage) { m.BatchID = "1" },
+		func(m *ReportMessage) { m.Destination = "+886900000000" },
+		func(m *ReportMessage) { m.StatusCode = StatusSent },
+		func(m *ReportMessage) { m.ReportTime = "20090210120001" },
+		func(m *ReportMessage) { m.MessageNo = "002" },
+	} {
+		c := createReportMessage()
+		modify(c)
+		if a.Key() == c.Key() {
+			t.Errorf("Key of %+v should differ from %+v", c, a)
+		}
+	}
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	s := NewMemoryDedupStore(2)
+	future := time.Now().Add(time.Hour)
+
+	s.Mark("a", future)
+	s.Mark("b", future)
+	s.Mark("expired", time.Now().Add(-time.Second))
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", false}, // evicted
+		{"b", true},
+		{"expired", false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		if got, _ := s.Seen(tt.key); got != tt.want {
+			t.Errorf("Seen(%v) returned %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if got, want := s.Len(), 1; got != want {
+		t.Errorf("Len returned %v, want %v", got, want)
+	}
+}
+
+func TestFileDedupStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+
+	s, err := OpenFileDedupStore(path, 10)
+	if err != nil {
+		t.Fatalf("OpenFileDedupStore returned unexpected error: %v", err)
+	}
+	s.Mark("a", time.Now().Add(time.Hour))
+	s.Mark("expired", time.Now().Add(-time.Second))
+	s.Close()
+
+	s, err = OpenFileDedupStore(path, 10)
+	if err != nil {
+		t.Fatalf("OpenFileDedupStore returned unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if seen, _ := s.Seen("a"); !seen {
+		t.Error("Seen(a) returned false after reopening, want true")
+	}
+	if seen, _ := s.Seen("expired"); seen {
+		t.Error("Seen(expired) returned true after reopening, want false")
+	}
+	if got, want := s.Len(), 1; got != want {
+		t.Errorf("Len returned %v, want %v", got, want)
+	}
+}
+
+func TestMemoryDedupStore_SeenOrMark(t *testing.T) {
+	s := NewMemoryDedupStore(10)
+	expiresAt := time.Now().Add(time.Hour)
+
+	var wg sync.WaitGroup
+	var claimed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if seen, _ := s.SeenOrMark("a", expiresAt); !seen {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Errorf("SeenOrMark claimed the key %d times, want 1", claimed)
+	}
+
+	s.Unmark("a")
+	if seen, _ := s.SeenOrMark("a", expiresAt); seen {
+		t.Error("SeenOrMark(a) returned true after Unmark, want false")
+	}
+}
+
+func TestFileDedupStore_compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+
+	s, err := OpenFileDedupStore(path, 2)
+	if err != nil {
+		t.Fatalf("OpenFileDedupStore returned unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	future := time.Now().Add(time.Hour)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := s.SeenOrMark(key, future); err != nil {
+			t.Fatalf("SeenOrMark returned unexpected error: %v", err)
+		}
+	}
+	s.Unmark("e")
+
+	data, _ := ioutil.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got >= 4 {
+		t.Errorf("File has %d lines, want less than 4", got)
+	}
+
+	r, err := OpenFileDedupStore(path, 2)
+	if err != nil {
+		t.Fatalf("OpenFileDedupStore returned unexpected error: %v", err)
+	}
+	defer r.Close()
+	for key, want := range map[string]bool{"c": false, "d": true, "e": false} {
+		if seen, _ := r.Seen(key); seen != want {
+			t.Errorf("Seen(%v) returned %v after reopening, want %v", key, seen, want)
+		}
+	}
+}
+
+func TestWebhookHandler_deduplicator(t *testing.T) {
+	for _, deliver := range []bool{false, true} {
+		var calls, duplicates int
+		fail := true
+		h := &WebhookHandler{
+			Deduplicator: &Deduplicator{DeliverDuplicates: deliver},
+			OnDeliveryReport: func(ctx context.Context, report *ReportMessage) error {
+				if fail {
+					fail = false
+					return context.DeadlineExceeded
+				}
+				calls++
+				if IsDuplicate(ctx) {
+					duplicates++
+				}
+				return nil
+			},
+		}
+
+		// The first attempt fails and must not be marked as handled.
+		wantCodes := []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK, http.StatusOK}
+		for i, want := range wantCodes {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newCallbackRequest(createReportMessage()))
+			if w.Code != want {
+				t.Errorf("ServeHTTP %d. returned status %d, want %d", i, w.Code, want)
+			}
+		}
+
+		wantCalls, wantDuplicates := 1, 0
+		if deliver {
+			wantCalls, wantDuplicates = 3, 2
+		}
+		if calls != wantCalls || duplicates != wantDuplicates {
+			t.Errorf("DeliverDuplicates=%v: handled %d reports with %d duplicates, want %d with %d",
+				deliver, calls, duplicates, wantCalls, wantDuplicates)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/csv"
 	"io"
+	"log/slog"
 	"net/url"
 	"strconv"
 )
@@ -25,12 +26,20 @@ type DeliveryStatusResponse struct {
 
 // GetDeliveryStatus retrieves the delivery status.
 func (c *Client) GetDeliveryStatus(ctx context.Context, batchID, pageNo string) (*DeliveryStatusResponse, error) {
-	return c.getDeliveryStatus(ctx, "API21/HTTP/getDeliveryStatus.ashx", batchID, pageNo)
+	return c.getDeliveryStatus(ctx, deliveryStatusURL(false), batchID, pageNo)
 }
 
 // GetMMSDeliveryStatus retrieves the MMS delivery status.
 func (c *Client) GetMMSDeliveryStatus(ctx context.Context, batchID, pageNo string) (*DeliveryStatusResponse, error) {
-	return c.getDeliveryStatus(ctx, "API21/HTTP/MMS/getDeliveryStatus.ashx", batchID, pageNo)
+	return c.getDeliveryStatus(ctx, deliveryStatusURL(true), batchID, pageNo)
+}
+
+// deliveryStatusURL returns the delivery status endpoint of the SMS or MMS API.
+func deliveryStatusURL(mms bool) string {
+	if mms {
+		return "API21/HTTP/MMS/getDeliveryStatus.ashx"
+	}
+	return "API21/HTTP/getDeliveryStatus.ashx"
 }
 
 func (c *Client) getDeliveryStatus(ctx context.Context, urlStr, batchID, pageNo string) (*DeliveryStatusResponse, error) {
@@ -81,6 +90,14 @@ func (c *Client) getDeliveryStatus(ctx context.Context, urlStr, batchID, pageNo
 	if err != nil {
 		return nil, err
 	}
+	if c.Logger != nil {
+		c.Logger.DebugContext(ctx, "every8d: delivery status",
+			slog.String("endpoint", urlStr),
+			slog.String("batch_id", batchID),
+			slog.String("page", pageNo),
+			slog.Int("count", result.Count),
+		)
+	}
 
 	return result, nil
 }
@@ -0,0 +1,168 @@
+package every8d
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// DeliveryStatusIterator walks every page of the delivery status of a batch.
+//
+// Use Next to advance the iterator and DeliveryStatus to retrieve the current record:
+//
+//	it := client.DeliveryStatuses(batchID)
+//	for it.Next(ctx) {
+//		record := it.DeliveryStatus()
+//		// Process record...
+//	}
+//	if err := it.Err(); err != nil {
+//		// Handle error...
+//	}
+type DeliveryStatusIterator struct {
+	// Concurrency is the number of pages fetched in parallel once the size of the
+	// batch is known. Values less than 1 are treated as 1.
+	Concurrency int
+
+	client  *Client
+	urlStr  string
+	batchID string
+
+	pageNo   int // next page number to fetch
+	pageSize int // number of records on the first page
+	count    int // total number of records reported by the API
+	fetched  int // number of records fetched so far
+
+	records []DeliveryStatus
+	current DeliveryStatus
+	done    bool
+	err     error
+}
+
+// DeliveryStatuses returns an iterator over the SMS delivery status of the batch.
+func (c *Client) DeliveryStatuses(batchID string) *DeliveryStatusIterator {
+	return c.newDeliveryStatusIterator(deliveryStatusURL(false), batchID)
+}
+
+// MMSDeliveryStatuses returns an iterator over the MMS delivery status of the batch.
+func (c *Client) MMSDeliveryStatuses(batchID string) *DeliveryStatusIterator {
+	return c.newDeliveryStatusIterator(deliveryStatusURL(true), batchID)
+}
+
+func (c *Client) newDeliveryStatusIterator(urlStr, batchID string) *DeliveryStatusIterator {
+	return &DeliveryStatusIterator{
+		Concurrency: 1,
+		client:      c,
+		urlStr:      urlStr,
+		batchID:     batchID,
+		pageNo:      1,
+	}
+}
+
+// Next advances the iterator to the next record, fetching further pages as needed.
+// It returns false when there are no more records or an error occurred.
+func (it *DeliveryStatusIterator) Next(ctx context.Context) bool {
+	for len(it.records) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch(ctx)
+	}
+
+	it.current, it.records = it.records[0], it.records[1:]
+
+	return true
+}
+
+// DeliveryStatus returns the current record.
+func (it *DeliveryStatusIterator) DeliveryStatus() DeliveryStatus {
+	return it.current
+}
+
+// Count returns the total number of records reported by the API.
+// It is only known after the first call to Next.
+func (it *DeliveryStatusIterator) Count() int {
+	return it.count
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *DeliveryStatusIterator) Err() error {
+	return it.err
+}
+
+// All consumes the iterator and returns the remaining records.
+func (it *DeliveryStatusIterator) All(ctx context.Context) ([]DeliveryStatus, error) {
+	var records []DeliveryStatus
+	for it.Next(ctx) {
+		records = append(records, it.DeliveryStatus())
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	return records, nil
+}
+
+// fetch retrieves the next page, or the next Concurrency pages once the number of pages is known.
+func (it *DeliveryStatusIterator) fetch(ctx context.Context) {
+	n := 1
+	if last := it.lastPage(); last > 0 {
+		n = it.Concurrency
+		if n < 1 {
+			n = 1
+		}
+		if remaining := last - it.pageNo + 1; remaining < n {
+			n = remaining
+		}
+		if n < 1 {
+			it.done = true
+			return
+		}
+	}
+
+	responses := make([]*DeliveryStatusResponse, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pageNo := strconv.Itoa(it.pageNo + i)
+			responses[i], errs[i] = it.client.getDeliveryStatus(ctx, it.urlStr, it.batchID, pageNo)
+		}(i)
+	}
+	wg.Wait()
+
+	first := it.pageNo == 1
+	it.pageNo += n
+
+	for i, resp := range responses {
+		if errs[i] != nil {
+			it.err = errs[i]
+			return
+		}
+		if first && i == 0 {
+			it.count = resp.Count
+			it.pageSize = len(resp.Records)
+		}
+		if len(resp.Records) == 0 {
+			it.done = true
+			return
+		}
+
+		it.records = append(it.records, resp.Records...)
+		it.fetched += len(resp.Records)
+
+		if it.count > 0 && it.fetched >= it.count {
+			it.done = true
+			return
+		}
+	}
+}
+
+// lastPage returns the last page number, or 0 if it is not known yet.
+func (it *DeliveryStatusIterator) lastPage() int {
+	if it.count <= 0 || it.pageSize <= 0 {
+		return 0
+	}
+	return (it.count + it.pageSize - 1) / it.pageSize
+}
@@ -0,0 +1,152 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// handleDeliveryStatusPages registers a handler serving count records split into pages of pageSize.
+// It returns a function reporting the page numbers requested so far.
+func handleDeliveryStatusPages(t *testing.T, mux *http.ServeMux, pattern string, count, pageSize int) func() map[string]int {
+	var mu sync.Mutex
+	requested := map[string]int{}
+
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		r.ParseForm()
+		if got, want := r.Form.Get("BID"), "00000000-0000-0000-0000-000000000000"; got != want {
+			t.Errorf("Request BID is %v, want %v", got, want)
+		}
+
+		pageNo := r.Form.Get("PNO")
+		mu.Lock()
+		requested[pageNo]++
+		mu.Unlock()
+
+		var page int
+		fmt.Sscanf(pageNo, "%d", &page)
+
+		fmt.Fprintf(w, "%d\n", count)
+		for i := (page - 1) * pageSize; i < page*pageSize && i < count; i++ {
+			fmt.Fprintf(w, "\t+8869876543%02d\t2017/12/18 23:14:17\t1\t100\n", i)
+		}
+	})
+
+	return func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requested
+	}
+}
+
+func wantDeliveryStatusRecords(count int) []DeliveryStatus {
+	var records []DeliveryStatus
+	for i := 0; i < count; i++ {
+		records = append(records, DeliveryStatus{
+			Mobile:   fmt.Sprintf("+8869876543%02d", i),
+			SendTime: "2017/12/18 23:14:17",
+			Cost:     1,
+			Status:   StatusMessageReceived,
+		})
+	}
+	return records
+}
+
+func TestDeliveryStatusIterator(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requested := handleDeliveryStatusPages(t, mux, "/API21/HTTP/getDeliveryStatus.ashx", 5, 2)
+
+	it := client.DeliveryStatuses("00000000-0000-0000-0000-000000000000")
+	got, err := it.All(context.Background())
+	if err != nil {
+		t.Fatalf("All returned unexpected error: %v", err)
+	}
+	if want := wantDeliveryStatusRecords(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("All returned %+v, want %+v", got, want)
+	}
+	if got, want := it.Count(), 5; got != want {
+		t.Errorf("Count returned %v, want %v", got, want)
+	}
+	if got, want := requested(), map[string]int{"1": 1, "2": 1, "3": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Requested pages %v, want %v", got, want)
+	}
+}
+
+func TestDeliveryStatusIterator_concurrency(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requested := handleDeliveryStatusPages(t, mux, "/API21/HTTP/MMS/getDeliveryStatus.ashx", 25, 3)
+
+	it := client.MMSDeliveryStatuses("00000000-0000-0000-0000-000000000000")
+	it.Concurrency = 4
+
+	got, err := it.All(context.Background())
+	if err != nil {
+		t.Fatalf("All returned unexpected error: %v", err)
+	}
+	if want := wantDeliveryStatusRecords(25); !reflect.DeepEqual(got, want) {
+		t.Errorf("All returned %+v, want %+v", got, want)
+	}
+	for pageNo, n := range requested() {
+		if n != 1 {
+			t.Errorf("Page %v requested %d times, want 1", pageNo, n)
+		}
+	}
+	if got, want := len(requested()), 9; got != want {
+		t.Errorf("Requested %d pages, want %d", got, want)
+	}
+}
+
+func TestDeliveryStatusIterator_emptyPage(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	// Count is larger than the number of available records.
+	requested := handleDeliveryStatusPages(t, mux, "/API21/HTTP/getDeliveryStatus.ashx", 3, 2)
+	mux.HandleFunc("/API21/HTTP/MMS/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "0\n")
+	})
+
+	got, err := client.MMSDeliveryStatuses("00000000-0000-0000-0000-000000000000").All(context.Background())
+	if err != nil {
+		t.Fatalf("All returned unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("All returned %+v, want no records", got)
+	}
+
+	got, err = client.DeliveryStatuses("00000000-0000-0000-0000-000000000000").All(context.Background())
+	if err != nil {
+		t.Fatalf("All returned unexpected error: %v", err)
+	}
+	if want := wantDeliveryStatusRecords(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("All returned %+v, want %+v", got, want)
+	}
+	if _, ok := requested()["3"]; ok {
+		t.Errorf("Requested page 3 after all records were fetched")
+	}
+}
+
+func TestDeliveryStatusIterator_error(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+	})
+
+	it := client.DeliveryStatuses("00000000-0000-0000-0000-000000000000")
+	if it.Next(context.Background()) {
+		t.Error("Next returned true, want false")
+	}
+	if err, ok := it.Err().(*ErrorResponse); !ok || err.ErrorCode != StatusServerSiteError {
+		t.Errorf("Err returned %v, want ErrorResponse with %v", it.Err(), StatusServerSiteError)
+	}
+}
@@ -0,0 +1,306 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultEventKeepAlive  = 15 * time.Second
+	defaultEventBufferSize = 64
+)
+
+// ReportFilter selects the reports of an EventStream subscription.
+type ReportFilter struct {
+	// BatchID, if not empty, selects the reports of the batch.
+	BatchID string
+
+	// Statuses, if not empty, selects the reports of the status codes.
+	Statuses []StatusCode
+}
+
+// ParseReportFilter parses the filter of the "batch_id" and "status" query parameters,
+// e.g. ?batch_id=<id>&status=100,999.
+func ParseReportFilter(query url.Values) (*ReportFilter, error) {
+	filter := new(ReportFilter)
+	if v := query["batch_id"]; len(v) > 0 {
+		filter.BatchID = v[0]
+	}
+	for _, v := range query["status"] {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			code, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status %q", field)
+			}
+			filter.Statuses = append(filter.Statuses, StatusCode(code))
+		}
+	}
+	return filter, nil
+}
+
+// Encode returns the query string of the filter.
+func (f *ReportFilter) Encode() string {
+	query := url.Values{}
+	if f.BatchID != "" {
+		query.Set("batch_id", f.BatchID)
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = strconv.Itoa(int(status))
+		}
+		query.Set("status", strings.Join(statuses, ","))
+	}
+	return query.Encode()
+}
+
+// Match reports whether the report is selected by the filter.
+func (f *ReportFilter) Match(report *ReportMessage) bool {
+	if f == nil {
+		return true
+	}
+	if f.BatchID != "" && f.BatchID != report.BatchID {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if status == report.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+type subscription struct {
+	filter  *ReportFilter
+	reports chan *ReportMessage
+}
+
+// EventStream broadcasts the reports to its subscribers. It serves them as
+// Server-Sent Events, each "report" event carrying the ReportMessage as JSON:
+//
+//	stream := every8d.NewEventStream()
+//	http.Handle("/callback", &every8d.WebhookHandler{Events: stream})
+//	http.Handle("/events", stream)
+//
+// Subscribers too slow to keep up miss the reports published meanwhile.
+type EventStream struct {
+	// KeepAlive is the interval of the comments sent to keep idle connections open.
+	// Defaults to 15 seconds.
+	KeepAlive time.Duration
+
+	// Masker, if not nil, masks the phone numbers of the published reports.
+	Masker *Masker
+
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+	id            uint64
+	dropped       uint64
+}
+
+// NewEventStream returns a new EventStream.
+func NewEventStream() *EventStream {
+	return &EventStream{subscriptions: make(map[*subscription]struct{})}
+}
+
+// Publish broadcasts the report to the subscribers. It can be used as a ReportHandlerFunc.
+func (s *EventStream) Publish(_ context.Context, report *ReportMessage) error {
+	report = s.Masker.MaskReport(report)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscriptions {
+		if !sub.filter.Match(report) {
+			continue
+		}
+		select {
+		case sub.reports <- report:
+		default:
+			s.dropped++
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the channel of the reports selected by the filter, all reports if
+// filter is nil. The cancel function ends the subscription and closes the channel.
+func (s *EventStream) Subscribe(filter *ReportFilter) (<-chan *ReportMessage, func()) {
+	sub := &subscription{filter: filter, reports: make(chan *ReportMessage, defaultEventBufferSize)}
+
+	s.mu.Lock()
+	s.subscriptions[sub] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return sub.reports, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscriptions, sub)
+			close(sub.reports)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Subscribers returns the number of subscribers.
+func (s *EventStream) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.subscriptions)
+}
+
+// Dropped returns the number of reports missed by slow subscribers.
+func (s *EventStream) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// ServeHTTP implements the http.Handler interface, streaming the reports selected by
+// the "batch_id" and "status" query parameters, see ParseReportFilter.
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	filter, err := ParseReportFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reports, cancel := s.Subscribe(filter)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultEventKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case report := <-reports:
+			data, err := json.Marshal(report)
+			if err != nil {
+				continue
+			}
+			id := atomic.AddUint64(&s.id, 1)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: report\ndata: %s\n\n", id, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// ReadEvents reads the reports of a Server-Sent Events stream served by an EventStream
+// and calls fn with each of them, until the end of the stream or an error from fn.
+func ReadEvents(r io.Reader, fn func(report *ReportMessage) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	event, data := "", ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if (event == "" || event == "report") && data != "" {
+				report := new(ReportMessage)
+				if err := json.Unmarshal([]byte(data), report); err != nil {
+					return err
+				}
+				if err := fn(report); err != nil {
+					return err
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	return scanner.Err()
+}
+
+// SubscribeEvents connects to the events URL of an EventStream and calls fn with the
+// reports selected by the filter, until the context is done, the stream ends or fn
+// returns an error. If httpClient is nil, http.DefaultClient is used.
+func SubscribeEvents(ctx context.Context, httpClient *http.Client, eventsURL string, filter *ReportFilter, fn func(report *ReportMessage) error) error {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if filter != nil {
+		if query := filter.Encode(); query != "" {
+			if strings.Contains(eventsURL, "?") {
+				eventsURL += "&" + query
+			} else {
+				eventsURL += "?" + query
+			}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, eventsURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	err = ReadEvents(resp.Body, fn)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
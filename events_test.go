@@ -0,0 +1,166 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReportFilter(t *testing.T) {
+	query, _ := url.ParseQuery("batch_id=b1&status=100,%20999&status=0")
+	filter, err := ParseReportFilter(query)
+	if err != nil {
+		t.Fatalf("ParseReportFilter returned unexpected error: %v", err)
+	}
+
+	want := &ReportFilter{BatchID: "b1", Statuses: []StatusCode{100, 999, 0}}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("ParseReportFilter returned %+v, want %+v", filter, want)
+	}
+
+	if _, err := ParseReportFilter(url.Values{"status": {"ok"}}); err == nil {
+		t.Errorf("ParseReportFilter expected error to be returned")
+	}
+}
+
+func TestReportFilter_Match(t *testing.T) {
+	report := createReportMessage()
+
+	tests := []struct {
+		filter *ReportFilter
+		want   bool
+	}{
+		{nil, true},
+		{&ReportFilter{}, true},
+		{&ReportFilter{BatchID: report.BatchID}, true},
+		{&ReportFilter{BatchID: "other"}, false},
+		{&ReportFilter{Statuses: []StatusCode{StatusReplayContent, StatusMessageReceived}}, true},
+		{&ReportFilter{BatchID: report.BatchID, Statuses: []StatusCode{StatusReplayContent}}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.filter.Match(report); got != tt.want {
+			t.Errorf("%d. Match returned %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"id: 1\nevent: report\ndata: {\"BatchID\":\"b1\",\"StatusCode\":100}\n\n" +
+		"event: other\ndata: {}\n\n" +
+		"data: {\"BatchID\":\"b2\",\n" +
+		"data: \"StatusCode\":999}\n\n"
+
+	var got []string
+	err := ReadEvents(strings.NewReader(stream), func(report *ReportMessage) error {
+		got = append(got, report.BatchID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadEvents returned unexpected error: %v", err)
+	}
+	if want := []string{"b1", "b2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadEvents read %v, want %v", got, want)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	stream := NewEventStream()
+	webhook := httptest.NewServer(&WebhookHandler{Events: stream})
+	defer webhook.Close()
+	events := httptest.NewServer(stream)
+	defer events.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reports := make(chan *ReportMessage)
+	done := make(chan error, 1)
+	go func() {
+		filter := &ReportFilter{Statuses: []StatusCode{StatusReplayContent}}
+		done <- SubscribeEvents(ctx, nil, events.URL, filter, func(report *ReportMessage) error {
+			reports <- report
+			return errors.New("stop")
+		})
+	}()
+
+	// Wait for the subscription.
+	for stream.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	simulator := NewSimulator(webhook.URL)
+	if err := simulator.Run(ctx, ReportSequence("b1", "+886987654321", "YES")); err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	select {
+	case report := <-reports:
+		if report.StatusCode != StatusReplayContent || report.ReplyMessage != "YES" {
+			t.Errorf("Subscriber received %+v, want the reply", report)
+		}
+	case <-ctx.Done():
+		t.Fatal("Subscriber received no report")
+	}
+	if err := <-done; err == nil || err.Error() != "stop" {
+		t.Errorf("SubscribeEvents returned %v, want stop", err)
+	}
+}
+
+func TestEventStream_Subscribe(t *testing.T) {
+	stream := NewEventStream()
+	reports, cancel := stream.Subscribe(&ReportFilter{BatchID: "b1"})
+
+	for i := 0; i < defaultEventBufferSize+2; i++ {
+		stream.Publish(context.Background(), NewDeliveryReport("b1", "+886987654321", StatusMessageReceived))
+	}
+	stream.Publish(context.Background(), NewDeliveryReport("b2", "+886987654321", StatusMessageReceived))
+
+	if got := stream.Dropped(); got != 2 {
+		t.Errorf("Dropped returned %d, want 2", got)
+	}
+
+	cancel()
+	cancel()
+	n := 0
+	for range reports {
+		n++
+	}
+	if n != defaultEventBufferSize {
+		t.Errorf("Subscriber received %d reports, want %d", n, defaultEventBufferSize)
+	}
+	if got := stream.Subscribers(); got != 0 {
+		t.Errorf("Subscribers returned %d, want 0", got)
+	}
+}
+
+func TestEventStream_Publish_masker(t *testing.T) {
+	stream := NewEventStream()
+	stream.Masker = &Masker{Policy: MaskFull}
+	reports, cancel := stream.Subscribe(nil)
+	defer cancel()
+
+	stream.Publish(context.Background(), NewDeliveryReport("b1", "+886987654321", StatusMessageReceived))
+	if got, want := (<-reports).Destination, "+************"; got != want {
+		t.Errorf("Subscriber received destination %q, want %q", got, want)
+	}
+}
+
+func TestWebhookHandler_eventsAfterDedup(t *testing.T) {
+	stream := NewEventStream()
+	reports, cancel := stream.Subscribe(nil)
+	defer cancel()
+
+	h := &WebhookHandler{Events: stream, Deduplicator: &Deduplicator{}}
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newCallbackRequest(createReportMessage()))
+	}
+	if n := len(reports); n != 1 {
+		t.Errorf("Subscriber received %d reports, want 1", n)
+	}
+}
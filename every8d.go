@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -30,6 +32,34 @@ type Client struct {
 
 	// User agent used when communicating with the EVERY8D API.
 	UserAgent string
+
+	// Suppression, if not nil, holds the numbers removed from the destination of every message.
+	Suppression SuppressionList
+
+	// Correlation, if not nil, records every message sent, see CorrelationStore.
+	Correlation CorrelationStore
+
+	// Metrics, if not nil, observes every API request, message sent and credit retrieved.
+	Metrics Metrics
+
+	// Logger, if not nil, logs every API request at the debug level, failed ones at the
+	// warn level, and every message sent at the info level.
+	Logger *slog.Logger
+
+	// Masker masks the phone numbers logged and returned in the errors. Defaults to
+	// DefaultMasker.
+	Masker *Masker
+
+	// CreditMonitor, if not nil, is updated with the credit left after every send.
+	CreditMonitor *CreditMonitor
+
+	// Ledger, if not nil, records the cost of every message sent, with the tags of
+	// its context, see WithTags.
+	Ledger Ledger
+
+	// OnRecordError, if not nil, is called when a sent message fails to be recorded
+	// in the Correlation store or the Ledger. The failure is logged too.
+	OnRecordError func(ctx context.Context, resp *SendResponse, err error)
 }
 
 // NewClient returns a new EVERY8D API client.
@@ -94,6 +124,23 @@ type Parser func(body io.Reader, v interface{}) error
 //
 // The provided ctx must be non-nil. If it is canceled or time out, ctx.Err() will be returned.
 func (c *Client) Do(ctx context.Context, req *http.Request, fn Parser, v interface{}) (*http.Response, error) {
+	if c.Metrics == nil && c.Logger == nil {
+		return c.do(ctx, req, fn, v)
+	}
+
+	start := time.Now()
+	resp, err := c.do(ctx, req, fn, v)
+	latency := time.Since(start)
+
+	endpoint := strings.TrimPrefix(req.URL.Path, c.BaseURL.Path)
+	if c.Metrics != nil {
+		c.Metrics.ObserveRequest(endpoint, latency, err)
+	}
+	c.logRequest(ctx, endpoint, latency, err)
+	return resp, err
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, fn Parser, v interface{}) (*http.Response, error) {
 	req = req.WithContext(ctx)
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -106,7 +153,7 @@ func (c *Client) Do(ctx context.Context, req *http.Request, fn Parser, v interfa
 		// If the error type is *url.Error, sanitize its URL before returning.
 		if e, ok := err.(*url.Error); ok {
 			if u, err := url.Parse(e.URL); err == nil {
-				e.URL = sanitizeURL(u).String()
+				e.URL = sanitizeURL(u, c.masker()).String()
 				return nil, e
 			}
 		}
@@ -116,6 +163,9 @@ func (c *Client) Do(ctx context.Context, req *http.Request, fn Parser, v interfa
 	defer resp.Body.Close()
 
 	if err := CheckResponse(resp); err != nil {
+		if e, ok := err.(*ErrorResponse); ok {
+			e.masker = c.masker()
+		}
 		return resp, err
 	}
 
@@ -126,8 +176,9 @@ func (c *Client) Do(ctx context.Context, req *http.Request, fn Parser, v interfa
 	return resp, nil
 }
 
-// sanitizeURL redacts the PWD parameter from the URL which may be exposed to the user.
-func sanitizeURL(uri *url.URL) *url.URL {
+// sanitizeURL redacts the PWD parameter and masks the DEST parameter of the URL which may
+// be exposed to the user.
+func sanitizeURL(uri *url.URL, m *Masker) *url.URL {
 	if uri == nil {
 		return nil
 	}
@@ -136,6 +187,10 @@ func sanitizeURL(uri *url.URL) *url.URL {
 		params.Set("PWD", "REDACTED")
 		uri.RawQuery = params.Encode()
 	}
+	if dest := params.Get("DEST"); len(dest) > 0 {
+		params.Set("DEST", m.MaskDestination(dest))
+		uri.RawQuery = params.Encode()
+	}
 	return uri
 }
 
@@ -144,15 +199,23 @@ type ErrorResponse struct {
 	Response  *http.Response
 	ErrorCode StatusCode
 	Message   string
+
+	masker *Masker
 }
 
+// Error returns the error message, with the phone numbers masked by the Masker of the
+// client, or DefaultMasker.
 func (r *ErrorResponse) Error() string {
+	m := r.masker
+	if m == nil {
+		m = DefaultMasker
+	}
 	return fmt.Sprintf("%v %v: %d %d %v",
 		r.Response.Request.Method,
-		sanitizeURL(r.Response.Request.URL),
+		sanitizeURL(r.Response.Request.URL, m),
 		r.Response.StatusCode,
 		r.ErrorCode,
-		r.Message)
+		m.MaskText(r.Message))
 }
 
 // CheckResponse checks the API response for errors.
@@ -250,6 +250,36 @@ func TestClient_Do_parseError(t *testing.T) {
 	}
 }
 
+func TestClient_Do_errorMasker(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "-4, 門號格式錯誤 0987654321")
+	})
+
+	tests := []struct {
+		masker *Masker
+		want   string
+	}{
+		{nil, "+8869****4321"},
+		{&Masker{Policy: MaskNone}, "0987654321"},
+		{&Masker{Policy: MaskFull}, "+************"},
+	}
+
+	for _, tt := range tests {
+		client.Masker = tt.masker
+		req, _ := client.NewRequest("GET", ".", nil)
+		_, err := client.Do(context.Background(), req, nil, nil)
+		if err == nil {
+			t.Fatal("Expected error response.")
+		}
+		if got := err.Error(); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("Error = %v, want suffix %v", got, tt.want)
+		}
+	}
+}
+
 // Test that an error caused by the internal http client's Do() function does not leak the client PWD.
 func TestClient_Do_sanitizeURL(t *testing.T) {
 	client := NewClient("username", "password", nil)
@@ -280,7 +310,7 @@ func TestSanitizeURL(t *testing.T) {
 		inURL, _ := url.Parse(tt.in)
 		want, _ := url.Parse(tt.want)
 
-		if got := sanitizeURL(inURL); !reflect.DeepEqual(got, want) {
+		if got := sanitizeURL(inURL, DefaultMasker); !reflect.DeepEqual(got, want) {
 			t.Errorf("sanitizeURL(%v) returned %v, want %v", tt.in, got, want)
 		}
 	}
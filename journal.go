@@ -0,0 +1,279 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJournalMaxSize = 64 << 20
+	journalFilePrefix     = "journal-"
+	journalFileSuffix     = ".jsonl"
+)
+
+// JournalEntry represents a report appended to a Journal.
+type JournalEntry struct {
+	// Offset of the entry, increasing by one for each report.
+	Offset uint64 `json:"offset"`
+
+	// Time the report was received.
+	ReceivedAt time.Time `json:"received_at"`
+
+	Report *ReportMessage `json:"report"`
+}
+
+// Journal is a durable append-only log of the received reports, stored as JSON Lines
+// files in a directory. Each append is synced to disk, and the files are rotated when
+// they reach MaxSize, so the reports can be replayed after an outage.
+type Journal struct {
+	dir     string
+	maxSize int64
+
+	// Masker, if not nil, masks the phone numbers of the recorded reports.
+	Masker *Masker
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	next uint64
+}
+
+// OpenJournal opens the journal stored in dir, creating it if needed.
+// Files are rotated once they reach maxSize bytes, 64 MiB if maxSize is not positive.
+func OpenJournal(dir string, maxSize int64) (*Journal, error) {
+	if maxSize <= 0 {
+		maxSize = defaultJournalMaxSize
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{dir: dir, maxSize: maxSize}
+
+	files, err := journalFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		last := files[len(files)-1]
+		j.next = last.first
+		size, err := readJournalFile(last.path, func(entry *JournalEntry) error {
+			j.next = entry.Offset + 1
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Drop the truncated line left by a crash during a write.
+		if err := os.Truncate(last.path, size); err != nil {
+			return nil, err
+		}
+		if j.file, err = os.OpenFile(last.path, os.O_APPEND|os.O_WRONLY, 0600); err != nil {
+			return nil, err
+		}
+		j.size = size
+	}
+
+	return j, nil
+}
+
+// Append appends the report and syncs it to disk.
+func (j *Journal) Append(report *ReportMessage) (*JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := &JournalEntry{Offset: j.next, ReceivedAt: time.Now(), Report: j.Masker.MaskReport(report)}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	line = append(line, '\n')
+
+	if j.file == nil || j.size+int64(len(line)) > j.maxSize && j.size > 0 {
+		if err := j.rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := j.file.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.file.Sync(); err != nil {
+		return nil, err
+	}
+
+	j.next++
+	return entry, nil
+}
+
+// rotate starts a new file named after the next offset.
+func (j *Journal) rotate() error {
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%s%020d%s", journalFilePrefix, j.next, journalFileSuffix)
+	f, err := os.OpenFile(filepath.Join(j.dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j.file, j.size = f, 0
+	return nil
+}
+
+// removeBefore removes the files holding only entries before the offset, except the
+// file being appended to.
+func (j *Journal) removeBefore(offset uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	files, err := journalFiles(j.dir)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(files) && files[i+1].first <= offset; i++ {
+		if err := os.Remove(files[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dir returns the directory of the journal.
+func (j *Journal) Dir() string {
+	return j.dir
+}
+
+// Close closes the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// ReplayOptions specifies the entries replayed by ReplayJournal.
+type ReplayOptions struct {
+	// Offset of the first entry to replay.
+	Offset uint64
+
+	// Since, if not zero, skips the entries received before.
+	Since time.Time
+}
+
+// ReplayJournal calls fn with the entries of the journal stored in dir, in order.
+// It stops at the first error returned by fn. The journal may be appended to concurrently.
+func ReplayJournal(ctx context.Context, dir string, opts *ReplayOptions, fn func(ctx context.Context, entry *JournalEntry) error) error {
+	if opts == nil {
+		opts = &ReplayOptions{}
+	}
+
+	files, err := journalFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		// Skip the files ending before the offset.
+		if i+1 < len(files) && files[i+1].first <= opts.Offset {
+			continue
+		}
+
+		_, err := readJournalFile(file.path, func(entry *JournalEntry) error {
+			if entry.Offset < opts.Offset || entry.ReceivedAt.Before(opts.Since) {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(ctx, entry)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayToURL returns a replay function delivering the reports to the callback URL,
+// see DeliverReport.
+func ReplayToURL(callbackURL string) func(ctx context.Context, entry *JournalEntry) error {
+	return func(ctx context.Context, entry *JournalEntry) error {
+		return DeliverReport(ctx, nil, callbackURL, entry.Report)
+	}
+}
+
+type journalFile struct {
+	path  string
+	first uint64
+}
+
+// journalFiles returns the files of the journal sorted by their first offset.
+func journalFiles(dir string) ([]journalFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, journalFilePrefix+"*"+journalFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []journalFile
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), journalFilePrefix), journalFileSuffix)
+		first, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, journalFile{path: path, first: first})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].first < files[j].first })
+	return files, nil
+}
+
+// readJournalFile calls fn with each entry of the file and returns the size of the
+// complete lines. A truncated last line, left by a crash during a write, is ignored.
+func readJournalFile(path string, fn func(entry *JournalEntry) error) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+
+		entry := new(JournalEntry)
+		if err := json.Unmarshal(line, entry); err != nil {
+			return size, fmt.Errorf("%s: %v", path, err)
+		}
+		if err := fn(entry); err != nil {
+			return size, err
+		}
+		size += int64(len(line))
+	}
+}
@@ -0,0 +1,215 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendReports(t *testing.T, j *Journal, n int) {
+	for i := 0; i < n; i++ {
+		report := createReportMessage()
+		report.MessageNo = string(rune('a' + i))
+		if _, err := j.Append(report); err != nil {
+			t.Fatalf("Append returned unexpected error: %v", err)
+		}
+	}
+}
+
+func replayMessageNos(t *testing.T, dir string, opts *ReplayOptions) []string {
+	var got []string
+	err := ReplayJournal(context.Background(), dir, opts, func(ctx context.Context, entry *JournalEntry) error {
+		got = append(got, entry.Report.MessageNo)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReplayJournal returned unexpected error: %v", err)
+	}
+	return got
+}
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+
+	// Small files to force rotation.
+	j, err := OpenJournal(dir, 300)
+	if err != nil {
+		t.Fatalf("OpenJournal returned unexpected error: %v", err)
+	}
+	appendReports(t, j, 3)
+	j.Close()
+
+	j, err = OpenJournal(dir, 300)
+	if err != nil {
+		t.Fatalf("OpenJournal returned unexpected error: %v", err)
+	}
+	appendReports(t, j, 2)
+	j.Close()
+
+	files, _ := journalFiles(dir)
+	if len(files) < 2 {
+		t.Errorf("Journal has %d files, want rotation", len(files))
+	}
+
+	if got, want := replayMessageNos(t, dir, nil), []string{"a", "b", "c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReplayJournal replayed %v, want %v", got, want)
+	}
+	if got, want := replayMessageNos(t, dir, &ReplayOptions{Offset: 3}), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReplayJournal from offset 3 replayed %v, want %v", got, want)
+	}
+	if got := replayMessageNos(t, dir, &ReplayOptions{Since: time.Now().Add(time.Hour)}); len(got) != 0 {
+		t.Errorf("ReplayJournal since an hour later replayed %v, want none", got)
+	}
+
+	var entry *JournalEntry
+	ReplayJournal(context.Background(), dir, &ReplayOptions{Offset: 4}, func(ctx context.Context, e *JournalEntry) error {
+		entry = e
+		return nil
+	})
+	want := createReportMessage()
+	want.MessageNo = "b"
+	if entry.Offset != 4 || !reflect.DeepEqual(reportValues(entry.Report), reportValues(want)) || !entry.Report.ReportedAt.Equal(want.ReportedAt) {
+		t.Errorf("ReplayJournal replayed %+v, want offset 4 with %+v", entry, want)
+	}
+}
+
+func TestJournal_truncatedLine(t *testing.T) {
+	dir := t.TempDir()
+
+	j, _ := OpenJournal(dir, 0)
+	appendReports(t, j, 1)
+	j.Close()
+
+	files, _ := journalFiles(dir)
+	f, _ := os.OpenFile(files[0].path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"offset":1,"rec`)
+	f.Close()
+
+	j, err := OpenJournal(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenJournal returned unexpected error: %v", err)
+	}
+	appendReports(t, j, 1)
+	j.Close()
+
+	if got, want := replayMessageNos(t, dir, nil), []string{"a", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReplayJournal replayed %v, want %v", got, want)
+	}
+}
+
+func TestReplayJournal_error(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := OpenJournal(dir, 0)
+	appendReports(t, j, 3)
+	j.Close()
+
+	n := 0
+	failure := errors.New("failure")
+	err := ReplayJournal(context.Background(), dir, nil, func(ctx context.Context, entry *JournalEntry) error {
+		n++
+		return failure
+	})
+	if err != failure || n != 1 {
+		t.Errorf("ReplayJournal returned %v after %d entries, want %v after 1", err, n, failure)
+	}
+}
+
+func TestReplayToURL(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := OpenJournal(dir, 0)
+	appendReports(t, j, 2)
+	j.Close()
+
+	var got []string
+	server := httptest.NewServer(&WebhookHandler{
+		OnDeliveryReport: func(ctx context.Context, report *ReportMessage) error {
+			got = append(got, report.MessageNo)
+			return nil
+		},
+	})
+	defer server.Close()
+
+	if err := ReplayJournal(context.Background(), dir, nil, ReplayToURL(server.URL+"/callback")); err != nil {
+		t.Fatalf("ReplayJournal returned unexpected error: %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Callback received %v, want %v", got, want)
+	}
+}
+
+func TestWebhookHandler_journal(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := OpenJournal(dir, 0)
+	defer j.Close()
+
+	h := &WebhookHandler{
+		Journal: j,
+		OnDeliveryReport: func(ctx context.Context, report *ReportMessage) error {
+			return errors.New("consumer down")
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newCallbackRequest(createReportMessage()))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("ServeHTTP returned status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	// The report is journaled even though the consumer failed.
+	if got := replayMessageNos(t, dir, nil); len(got) != 1 {
+		t.Errorf("ReplayJournal replayed %v, want 1 report", got)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.jsonl")); len(matches) != 1 || !strings.HasPrefix(filepath.Base(matches[0]), "journal-") {
+		t.Errorf("Journal files are %v", matches)
+	}
+}
+
+func TestWebhookHandler_journalDuplicates(t *testing.T) {
+	for _, deliver := range []bool{false, true} {
+		dir := t.TempDir()
+		j, _ := OpenJournal(dir, 0)
+
+		h := &WebhookHandler{
+			Journal:      j,
+			Deduplicator: &Deduplicator{DeliverDuplicates: deliver},
+		}
+		for i := 0; i < 3; i++ {
+			h.ServeHTTP(httptest.NewRecorder(), newCallbackRequest(createReportMessage()))
+		}
+		j.Close()
+
+		if got := replayMessageNos(t, dir, nil); len(got) != 1 {
+			t.Errorf("DeliverDuplicates=%v: ReplayJournal replayed %v, want 1 report", deliver, got)
+		}
+	}
+}
+
+func TestJournal_masker(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := OpenJournal(dir, 0)
+	j.Masker = DefaultMasker
+
+	report := createReportMessage()
+	if _, err := j.Append(report); err != nil {
+		t.Fatalf("Append returned unexpected error: %v", err)
+	}
+	j.Close()
+
+	if got, want := report.Destination, "+886987654321"; got != want {
+		t.Errorf("Append changed the destination to %v, want %v", got, want)
+	}
+
+	files, _ := journalFiles(dir)
+	data, _ := ioutil.ReadFile(files[0].path)
+	if strings.Contains(string(data), "987654321") || !strings.Contains(string(data), "+8869****4321") {
+		t.Errorf("Journal recorded %s, want the destination masked", data)
+	}
+}
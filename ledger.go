@@ -0,0 +1,452 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tags label the sends for cost accounting, e.g. {"team": "growth", "env": "prod"}.
+type Tags map[string]string
+
+type tagsKey struct{}
+
+// WithTags returns a copy of ctx carrying the tags, added to those ctx already carries.
+// The sends made with the context are recorded with the tags in the Client.Ledger.
+func WithTags(ctx context.Context, tags Tags) context.Context {
+	merged := Tags{}
+	for k, v := range TagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// TagsFromContext returns the tags carried by ctx, nil if there are none.
+func TagsFromContext(ctx context.Context) Tags {
+	tags, _ := ctx.Value(tagsKey{}).(Tags)
+	return tags
+}
+
+// LedgerEntry records the cost of a send.
+type LedgerEntry struct {
+	BatchID string    `json:"batch_id"`
+	MMS     bool      `json:"mms,omitempty"`
+	Tags    Tags      `json:"tags,omitempty"`
+	Sent    int       `json:"sent"`
+	Unsent  int       `json:"unsent"`
+	Cost    float64   `json:"cost"`
+	SentAt  time.Time `json:"sent_at"`
+
+	// ReconciledCost is the sum of the costs of the delivery statuses of the batch,
+	// nil until it is reconciled.
+	ReconciledCost *float64 `json:"reconciled_cost,omitempty"`
+}
+
+// NewLedgerEntry returns the entry of a send, tagged with the tags carried by ctx.
+func NewLedgerEntry(ctx context.Context, mms bool, resp *SendResponse) *LedgerEntry {
+	return &LedgerEntry{
+		BatchID: resp.BatchID,
+		MMS:     mms,
+		Tags:    TagsFromContext(ctx),
+		Sent:    resp.Sent,
+		Unsent:  resp.Unsent,
+		Cost:    resp.Cost,
+		SentAt:  time.Now(),
+	}
+}
+
+// Ledger records the cost of the sends, see Client.Ledger.
+type Ledger interface {
+	// Record records the entry of a send.
+	Record(ctx context.Context, entry *LedgerEntry) error
+
+	// Reconcile sets the cost of the batch from its delivery statuses.
+	Reconcile(ctx context.Context, batchID string, cost float64) error
+
+	// Entries returns the entries, in the order they were recorded.
+	Entries(ctx context.Context) ([]*LedgerEntry, error)
+}
+
+// account records the send in the Client.Ledger.
+func (c *Client) account(ctx context.Context, mms bool, resp *SendResponse) error {
+	if c.Ledger == nil || resp.BatchID == "" {
+		return nil
+	}
+	return c.Ledger.Record(ctx, NewLedgerEntry(ctx, mms, resp))
+}
+
+// ReconcileLedger sets the cost of the entries not reconciled yet from the delivery
+// statuses of their batch, and returns the number of entries reconciled.
+// Batches whose recipients do not all have a final status yet are left for later.
+// A batch failing to be reconciled is skipped, and its error returned with the others.
+func (c *Client) ReconcileLedger(ctx context.Context, ledger Ledger) (int, error) {
+	entries, err := ledger.Entries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	var errs []error
+	for _, entry := range entries {
+		if entry.ReconciledCost != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return n, errors.Join(append(errs, err)...)
+		}
+
+		it := c.DeliveryStatuses(entry.BatchID)
+		if entry.MMS {
+			it = c.MMSDeliveryStatuses(entry.BatchID)
+		}
+		statuses, err := it.All(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("batch %s: %w", entry.BatchID, err))
+			continue
+		}
+		if !finalStatuses(statuses, entry.Sent) {
+			continue
+		}
+
+		cost := 0.0
+		for _, status := range statuses {
+			cost += status.Cost
+		}
+		if err := ledger.Reconcile(ctx, entry.BatchID, cost); err != nil {
+			errs = append(errs, fmt.Errorf("batch %s: %w", entry.BatchID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+// finalStatuses reports whether the statuses of the sent recipients are all final.
+func finalStatuses(statuses []DeliveryStatus, sent int) bool {
+	if len(statuses) == 0 || len(statuses) < sent {
+		return false
+	}
+	for _, status := range statuses {
+		if status.Status.IsPending() {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryLedger is a Ledger kept in memory.
+type MemoryLedger struct {
+	mu      sync.RWMutex
+	entries []*LedgerEntry
+	batches map[string]*LedgerEntry
+}
+
+// NewMemoryLedger returns a new MemoryLedger.
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{batches: make(map[string]*LedgerEntry)}
+}
+
+// Record implements the Ledger interface.
+func (l *MemoryLedger) Record(_ context.Context, entry *LedgerEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := *entry
+	l.entries = append(l.entries, &e)
+	l.batches[e.BatchID] = &e
+	return nil
+}
+
+// Reconcile implements the Ledger interface.
+func (l *MemoryLedger) Reconcile(_ context.Context, batchID string, cost float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.batches[batchID]
+	if !ok {
+		return fmt.Errorf("batch %s not found in the ledger", batchID)
+	}
+	entry.ReconciledCost = &cost
+	return nil
+}
+
+// Entries implements the Ledger interface.
+func (l *MemoryLedger) Entries(_ context.Context) ([]*LedgerEntry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]*LedgerEntry, len(l.entries))
+	for i, entry := range l.entries {
+		e := *entry
+		entries[i] = &e
+	}
+	return entries, nil
+}
+
+// ledgerRecord is a line of the FileLedger.
+type ledgerRecord struct {
+	Entry          *LedgerEntry `json:"entry,omitempty"`
+	BatchID        string       `json:"batch_id,omitempty"`
+	ReconciledCost *float64     `json:"reconciled_cost,omitempty"`
+	At             time.Time    `json:"at"`
+}
+
+// FileLedger is a Ledger persisted to an append-only JSON Lines file, loaded in memory
+// when opened.
+type FileLedger struct {
+	*MemoryLedger
+
+	// Masker, if not nil, masks the phone numbers in the tags of the recorded entries.
+	Masker *Masker
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileLedger opens the ledger at path, creating it if needed.
+func OpenFileLedger(path string) (*FileLedger, error) {
+	l := &FileLedger{MemoryLedger: NewMemoryLedger()}
+
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+func (l *FileLedger) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		record := new(ledgerRecord)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		switch {
+		case record.Entry != nil:
+			l.MemoryLedger.Record(ctx, record.Entry)
+		case record.ReconciledCost != nil:
+			l.MemoryLedger.Reconcile(ctx, record.BatchID, *record.ReconciledCost)
+		}
+	}
+	return scanner.Err()
+}
+
+// append writes the record to the file.
+func (l *FileLedger) append(record *ledgerRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Record implements the Ledger interface.
+func (l *FileLedger) Record(ctx context.Context, entry *LedgerEntry) error {
+	if l.Masker != nil && len(entry.Tags) > 0 {
+		masked := *entry
+		masked.Tags = make(Tags, len(entry.Tags))
+		for k, v := range entry.Tags {
+			masked.Tags[k] = l.Masker.MaskText(v)
+		}
+		entry = &masked
+	}
+	if err := l.MemoryLedger.Record(ctx, entry); err != nil {
+		return err
+	}
+	return l.append(&ledgerRecord{Entry: entry, At: entry.SentAt})
+}
+
+// Reconcile implements the Ledger interface.
+func (l *FileLedger) Reconcile(ctx context.Context, batchID string, cost float64) error {
+	if err := l.MemoryLedger.Reconcile(ctx, batchID, cost); err != nil {
+		return err
+	}
+	return l.append(&ledgerRecord{BatchID: batchID, ReconciledCost: &cost, At: time.Now()})
+}
+
+// Close closes the file.
+func (l *FileLedger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// Periods of a usage report.
+const (
+	UsageTotal   = ""
+	UsageByDay   = "day"
+	UsageByMonth = "month"
+)
+
+// UsageQuery selects and groups the ledger entries of a usage report.
+type UsageQuery struct {
+	// GroupBy are the names of the tags the usage is split by, e.g. "team".
+	GroupBy []string
+
+	// Period the usage is split by: UsageTotal, UsageByDay or UsageByMonth.
+	Period string
+
+	// From and To, if not zero, select the entries sent in [From, To).
+	From time.Time
+	To   time.Time
+
+	// Location of the day and month boundaries. Defaults to the time zone of Taiwan.
+	Location *time.Location
+}
+
+// UsageRow is the usage of a period and of a combination of tag values.
+type UsageRow struct {
+	Period  string  `json:"period,omitempty"`
+	Tags    Tags    `json:"tags,omitempty"`
+	Batches int     `json:"batches"`
+	Sent    int     `json:"sent"`
+	Unsent  int     `json:"unsent"`
+	Cost    float64 `json:"cost"`
+
+	// Reconciled is the number of batches whose cost was reconciled, and
+	// ReconciledCost the sum of their reconciled costs.
+	Reconciled     int     `json:"reconciled"`
+	ReconciledCost float64 `json:"reconciled_cost"`
+}
+
+// Usage aggregates the cost of the ledger entries selected by the query.
+func Usage(ctx context.Context, ledger Ledger, query *UsageQuery) ([]*UsageRow, error) {
+	if query == nil {
+		query = &UsageQuery{}
+	}
+	var layout string
+	switch query.Period {
+	case UsageTotal:
+	case UsageByDay:
+		layout = "2006-01-02"
+	case UsageByMonth:
+		layout = "2006-01"
+	default:
+		return nil, fmt.Errorf("unknown usage period %q", query.Period)
+	}
+	loc := query.Location
+	if loc == nil {
+		loc = taipei
+	}
+
+	entries, err := ledger.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]*UsageRow)
+	for _, entry := range entries {
+		if !query.From.IsZero() && entry.SentAt.Before(query.From) ||
+			!query.To.IsZero() && !entry.SentAt.Before(query.To) {
+			continue
+		}
+
+		row := &UsageRow{}
+		if layout != "" {
+			row.Period = entry.SentAt.In(loc).Format(layout)
+		}
+		key := []string{row.Period}
+		if len(query.GroupBy) > 0 {
+			row.Tags = Tags{}
+			for _, name := range query.GroupBy {
+				row.Tags[name] = entry.Tags[name]
+				key = append(key, entry.Tags[name])
+			}
+		}
+		k := strings.Join(key, "\x00")
+		if existing, ok := rows[k]; ok {
+			row = existing
+		} else {
+			rows[k] = row
+		}
+
+		row.Batches++
+		row.Sent += entry.Sent
+		row.Unsent += entry.Unsent
+		row.Cost += entry.Cost
+		if entry.ReconciledCost != nil {
+			row.Reconciled++
+			row.ReconciledCost += *entry.ReconciledCost
+		}
+	}
+
+	keys := make([]string, 0, len(rows))
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*UsageRow, len(keys))
+	for i, k := range keys {
+		result[i] = rows[k]
+	}
+	return result, nil
+}
+
+// WriteUsageCSV writes the usage rows as CSV, with a column for the period and for
+// each of the groupBy tags.
+func WriteUsageCSV(w io.Writer, rows []*UsageRow, groupBy []string) error {
+	cw := csv.NewWriter(w)
+
+	header := append([]string{"period"}, groupBy...)
+	header = append(header, "batches", "sent", "unsent", "cost", "reconciled", "reconciled_cost")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := []string{row.Period}
+		for _, name := range groupBy {
+			record = append(record, row.Tags[name])
+		}
+		record = append(record,
+			strconv.Itoa(row.Batches),
+			strconv.Itoa(row.Sent),
+			strconv.Itoa(row.Unsent),
+			strconv.FormatFloat(row.Cost, 'f', 2, 64),
+			strconv.Itoa(row.Reconciled),
+			strconv.FormatFloat(row.ReconciledCost, 'f', 2, 64),
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,245 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTags(t *testing.T) {
+	ctx := WithTags(context.Background(), Tags{"team": "growth", "env": "dev"})
+	ctx = WithTags(ctx, Tags{"env": "prod"})
+
+	if got, want := TagsFromContext(ctx), (Tags{"team": "growth", "env": "prod"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagsFromContext returned %v, want %v", got, want)
+	}
+	if got := TagsFromContext(context.Background()); got != nil {
+		t.Errorf("TagsFromContext returned %v, want nil", got)
+	}
+}
+
+func TestClient_Send_ledger(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "87.00,2,2,1,sms")
+	})
+	mux.HandleFunc("/API21/HTTP/MMS/sendMMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "84.00,1,3,0,mms")
+	})
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "2\n\t+886987654321\t2017/12/18 23:14:17\t1\t100\n\t+886912345678\t2017/12/18 23:14:17\t1.5\t100")
+	})
+	mux.HandleFunc("/API21/HTTP/MMS/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "0")
+	})
+
+	ledger := NewMemoryLedger()
+	client.Ledger = ledger
+
+	ctx := WithTags(context.Background(), Tags{"team": "growth"})
+	if _, err := client.Send(ctx, Message{Content: "Hello", Destination: "0987654321,0912345678,0900000000"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if _, err := client.SendMMS(context.Background(), MMS{Content: "Hello", Destination: "0987654321"}); err != nil {
+		t.Fatalf("SendMMS returned unexpected error: %v", err)
+	}
+
+	n, err := client.ReconcileLedger(context.Background(), ledger)
+	if err != nil {
+		t.Fatalf("ReconcileLedger returned unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("ReconcileLedger reconciled %d entries, want 1", n)
+	}
+
+	entries, _ := ledger.Entries(context.Background())
+	if len(entries) != 2 {
+		t.Fatalf("Entries returned %d entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		entry.SentAt = time.Time{}
+	}
+	reconciled := 2.5
+	want := []*LedgerEntry{
+		{BatchID: "sms", Tags: Tags{"team": "growth"}, Sent: 2, Unsent: 1, Cost: 2, ReconciledCost: &reconciled},
+		{BatchID: "mms", MMS: true, Sent: 1, Cost: 3},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Entries returned %+v, want %+v", entries, want)
+	}
+}
+
+func TestClient_ReconcileLedger(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("BID") {
+		case "pending":
+			fmt.Fprint(w, "2\n\t+886987654321\t2017/12/18 23:14:17\t1\t100\n\t+886912345678\t2017/12/18 23:14:17\t0\t0")
+		case "partial", "done":
+			fmt.Fprint(w, "1\n\t+886987654321\t2017/12/18 23:14:17\t1\t100")
+		default:
+			fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+		}
+	})
+
+	ctx := context.Background()
+	ledger := NewMemoryLedger()
+	for _, entry := range []*LedgerEntry{
+		{BatchID: "pending", Sent: 2, Cost: 2},
+		{BatchID: "partial", Sent: 2, Cost: 2},
+		{BatchID: "failing", Sent: 1, Cost: 1},
+		{BatchID: "done", Sent: 1, Cost: 1},
+	} {
+		ledger.Record(ctx, entry)
+	}
+
+	// The failing batch does not hold up the others.
+	n, err := client.ReconcileLedger(ctx, ledger)
+	if err == nil || !strings.Contains(err.Error(), "batch failing") {
+		t.Errorf("ReconcileLedger returned %v, want the error of the failing batch", err)
+	}
+	if n != 1 {
+		t.Errorf("ReconcileLedger reconciled %d entries, want 1", n)
+	}
+
+	entries, _ := ledger.Entries(ctx)
+	for _, entry := range entries {
+		if reconciled := entry.ReconciledCost != nil; reconciled != (entry.BatchID == "done") {
+			t.Errorf("Entry %s reconciled is %v", entry.BatchID, reconciled)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewMemoryLedger()
+	day := func(d int) time.Time { return time.Date(2026, 9, d, 12, 0, 0, 0, taipei) }
+
+	for _, entry := range []*LedgerEntry{
+		{BatchID: "1", Tags: Tags{"team": "growth"}, Sent: 10, Cost: 10, SentAt: day(29)},
+		{BatchID: "2", Tags: Tags{"team": "growth"}, Sent: 5, Unsent: 1, Cost: 5, SentAt: day(30)},
+		{BatchID: "3", Tags: Tags{"team": "ops"}, Sent: 1, Cost: 2, SentAt: day(30)},
+		{BatchID: "4", Sent: 1, Cost: 1, SentAt: day(30).AddDate(0, 0, 1)},
+	} {
+		ledger.Record(ctx, entry)
+	}
+	ledger.Reconcile(ctx, "1", 9)
+
+	tests := []struct {
+		query *UsageQuery
+		want  []*UsageRow
+	}{
+		{
+			nil,
+			[]*UsageRow{{Batches: 4, Sent: 17, Unsent: 1, Cost: 18, Reconciled: 1, ReconciledCost: 9}},
+		},
+		{
+			&UsageQuery{GroupBy: []string{"team"}, Period: UsageByMonth},
+			[]*UsageRow{
+				{Period: "2026-09", Tags: Tags{"team": "growth"}, Batches: 2, Sent: 15, Unsent: 1, Cost: 15, Reconciled: 1, ReconciledCost: 9},
+				{Period: "2026-09", Tags: Tags{"team": "ops"}, Batches: 1, Sent: 1, Cost: 2},
+				{Period: "2026-10", Tags: Tags{"team": ""}, Batches: 1, Sent: 1, Cost: 1},
+			},
+		},
+		{
+			&UsageQuery{Period: UsageByDay, From: day(30), To: day(30).AddDate(0, 0, 1)},
+			[]*UsageRow{{Period: "2026-09-30", Batches: 2, Sent: 6, Unsent: 1, Cost: 7}},
+		},
+	}
+
+	for i, test := range tests {
+		got, err := Usage(ctx, ledger, test.query)
+		if err != nil {
+			t.Fatalf("Usage %d. returned unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Usage %d. returned %+v, want %+v", i, got, test.want)
+		}
+	}
+
+	if _, err := Usage(ctx, ledger, &UsageQuery{Period: "week"}); err == nil {
+		t.Error("Expected error to be returned.")
+	}
+}
+
+func TestWriteUsageCSV(t *testing.T) {
+	rows := []*UsageRow{
+		{Period: "2026-09", Tags: Tags{"team": "growth"}, Batches: 2, Sent: 15, Unsent: 1, Cost: 15, Reconciled: 1, ReconciledCost: 9},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteUsageCSV(&buf, rows, []string{"team"}); err != nil {
+		t.Fatalf("WriteUsageCSV returned unexpected error: %v", err)
+	}
+
+	want := "period,team,batches,sent,unsent,cost,reconciled,reconciled_cost\n2026-09,growth,2,15,1,15.00,1,9.00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteUsageCSV returned %q, want %q", got, want)
+	}
+}
+
+func TestFileLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ctx := context.Background()
+
+	l, err := OpenFileLedger(path)
+	if err != nil {
+		t.Fatalf("OpenFileLedger returned unexpected error: %v", err)
+	}
+	l.Record(ctx, &LedgerEntry{BatchID: "1", Tags: Tags{"team": "growth"}, Sent: 1, Cost: 1})
+	l.Reconcile(ctx, "1", 1.5)
+	if err := l.Reconcile(ctx, "unknown", 1); err == nil {
+		t.Error("Expected error to be returned.")
+	}
+	l.Close()
+
+	l, err = OpenFileLedger(path)
+	if err != nil {
+		t.Fatalf("OpenFileLedger returned unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	entries, _ := l.Entries(ctx)
+	if len(entries) != 1 || entries[0].ReconciledCost == nil || *entries[0].ReconciledCost != 1.5 || entries[0].Tags["team"] != "growth" {
+		t.Errorf("Entries returned %+v", entries)
+	}
+}
+
+func TestFileLedger_masker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ctx := context.Background()
+
+	l, err := OpenFileLedger(path)
+	if err != nil {
+		t.Fatalf("OpenFileLedger returned unexpected error: %v", err)
+	}
+	defer l.Close()
+	l.Masker = DefaultMasker
+
+	tags := Tags{"customer": "0987654321"}
+	if err := l.Record(ctx, &LedgerEntry{BatchID: "1", Tags: tags, Sent: 1, Cost: 1}); err != nil {
+		t.Fatalf("Record returned unexpected error: %v", err)
+	}
+
+	entries, _ := l.Entries(ctx)
+	if got, want := entries[0].Tags["customer"], "+8869****4321"; got != want {
+		t.Errorf("Tag is %v, want %v", got, want)
+	}
+	if b, _ := ioutil.ReadFile(path); bytes.Contains(b, []byte("987654321")) {
+		t.Errorf("File contains unmasked numbers:\n%s", b)
+	}
+	if tags["customer"] != "0987654321" {
+		t.Errorf("Record modified the tags to %v", tags)
+	}
+}
@@ -0,0 +1,66 @@
+package every8d
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logRequest logs an API request at the debug level, or at the warn level if it failed.
+func (c *Client) logRequest(ctx context.Context, endpoint string, latency time.Duration, err error) {
+	if c.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", endpoint),
+		slog.Duration("latency", latency),
+	}
+	if err == nil {
+		c.Logger.LogAttrs(ctx, slog.LevelDebug, "every8d: request", attrs...)
+		return
+	}
+	if e, ok := err.(*ErrorResponse); ok {
+		attrs = append(attrs, slog.Int("status", int(e.ErrorCode)), slog.String("message", c.masker().MaskText(e.Message)))
+	} else {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.Logger.LogAttrs(ctx, slog.LevelWarn, "every8d: request failed", attrs...)
+}
+
+// logSend logs a sent message at the info level.
+func (c *Client) logSend(ctx context.Context, endpoint, destination string, resp *SendResponse) {
+	if c.Logger == nil {
+		return
+	}
+
+	c.Logger.LogAttrs(ctx, slog.LevelInfo, "every8d: sent",
+		slog.String("endpoint", endpoint),
+		slog.String("batch_id", resp.BatchID),
+		slog.String("destinations", c.masker().MaskDestination(destination)),
+		slog.Int("sent", resp.Sent),
+		slog.Int("unsent", resp.Unsent),
+		slog.Float64("cost", resp.Cost),
+		slog.Float64("credit", resp.Credit),
+	)
+}
+
+// logRecordError logs a sent message failing to be recorded at the error level.
+func (c *Client) logRecordError(ctx context.Context, resp *SendResponse, err error) {
+	if c.Logger == nil {
+		return
+	}
+
+	c.Logger.LogAttrs(ctx, slog.LevelError, "every8d: record failed",
+		slog.String("batch_id", resp.BatchID),
+		slog.String("error", c.masker().MaskText(err.Error())),
+	)
+}
+
+// masker returns the masker of the phone numbers logged.
+func (c *Client) masker() *Masker {
+	if c.Masker != nil {
+		return c.Masker
+	}
+	return DefaultMasker
+}
@@ -0,0 +1,73 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_Logger(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	client.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("MSG") == "" {
+			fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+			return
+		}
+		fmt.Fprint(w, "87.00,2,2,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	ctx := context.Background()
+	client.Send(ctx, Message{Content: "Hello", Destination: "0987654321,+886912345678"})
+	client.Send(ctx, Message{Destination: "0987654321"})
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Unmarshal returned unexpected error: %v", err)
+		}
+		delete(record, "time")
+		delete(record, "latency")
+		records = append(records, record)
+	}
+
+	want := []map[string]interface{}{
+		{
+			"level":    "DEBUG",
+			"msg":      "every8d: request",
+			"endpoint": "API21/HTTP/sendSMS.ashx",
+		},
+		{
+			"level":        "INFO",
+			"msg":          "every8d: sent",
+			"endpoint":     "API21/HTTP/sendSMS.ashx",
+			"batch_id":     "00000000-0000-0000-0000-000000000000",
+			"destinations": "+8869****4321,+8869****5678",
+			"sent":         2.0,
+			"unsent":       0.0,
+			"cost":         2.0,
+			"credit":       87.0,
+		},
+		{
+			"level":    "WARN",
+			"msg":      "every8d: request failed",
+			"endpoint": "API21/HTTP/sendSMS.ashx",
+			"status":   -99.0,
+			"message":  "主機端發生不明錯誤，請與廠商窗口聯繫。",
+		},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Logger recorded %v, want %v", records, want)
+	}
+}
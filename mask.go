@@ -0,0 +1,171 @@
+package every8d
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaskPolicy is how a Masker hides the phone numbers.
+type MaskPolicy int
+
+const (
+	// MaskNone shows the numbers as they are.
+	MaskNone MaskPolicy = iota
+
+	// MaskPartial replaces the middle digits with '*', e.g. +8869****5678.
+	MaskPartial
+
+	// MaskFull replaces all digits with '*'.
+	MaskFull
+
+	// MaskHash replaces the numbers with a salted hash, e.g. h:3f9a0c6e12b7d845.
+	// The same number always has the same hash, to correlate the records without
+	// revealing it.
+	MaskHash
+)
+
+var maskPolicyText = map[MaskPolicy]string{
+	MaskNone:    "none",
+	MaskPartial: "partial",
+	MaskFull:    "full",
+	MaskHash:    "hash",
+}
+
+// String returns the name of the policy.
+func (p MaskPolicy) String() string {
+	if str, ok := maskPolicyText[p]; ok {
+		return str
+	}
+	return "unknown"
+}
+
+// ParseMaskPolicy parses the name of a policy: "none", "partial", "full" or "hash".
+func ParseMaskPolicy(s string) (MaskPolicy, error) {
+	for policy, str := range maskPolicyText {
+		if strings.EqualFold(s, str) {
+			return policy, nil
+		}
+	}
+	return MaskNone, fmt.Errorf("unknown mask policy %q", s)
+}
+
+const (
+	defaultMaskKeepPrefix = 4
+	defaultMaskKeepSuffix = 4
+	maskHashLength        = 16
+)
+
+// DefaultMasker partially masks the numbers, e.g. +8869****5678.
+var DefaultMasker = &Masker{Policy: MaskPartial}
+
+// mobilePattern matches the phone numbers in a text.
+var mobilePattern = regexp.MustCompile(`\+?\d[\d-]{7,}\d`)
+
+// Masker masks the phone numbers in logs, outputs and persisted records.
+// A nil *Masker shows the numbers as they are.
+type Masker struct {
+	Policy MaskPolicy
+
+	// KeepPrefix and KeepSuffix are the numbers of leading and trailing digits shown
+	// by MaskPartial. Default to 4.
+	KeepPrefix int
+	KeepSuffix int
+
+	// Salt is the secret key of the MaskHash hashes.
+	Salt []byte
+}
+
+// Mask returns the masked number. The number is normalized first, see NormalizeMobile.
+// A number already masked, e.g. read back from a masked record, is returned as it is.
+func (m *Masker) Mask(mobile string) string {
+	if m == nil || m.Policy == MaskNone || mobile == "" || isMasked(mobile) {
+		return mobile
+	}
+	mobile = NormalizeMobile(mobile)
+
+	switch m.Policy {
+	case MaskHash:
+		mac := hmac.New(sha256.New, m.Salt)
+		mac.Write([]byte(mobile))
+		return "h:" + hex.EncodeToString(mac.Sum(nil))[:maskHashLength]
+	case MaskFull:
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return '*'
+			}
+			return r
+		}, mobile)
+	}
+
+	prefix, suffix := m.KeepPrefix, m.KeepSuffix
+	if prefix <= 0 {
+		prefix = defaultMaskKeepPrefix
+	}
+	if suffix <= 0 {
+		suffix = defaultMaskKeepSuffix
+	}
+	digits := 0
+	for _, r := range mobile {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	// Hide at least half the digits of the short numbers.
+	if prefix+suffix >= digits {
+		prefix = 0
+		if suffix > digits/2 {
+			suffix = digits / 2
+		}
+	}
+
+	i := 0
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return r
+		}
+		i++
+		if i > prefix && i <= digits-suffix {
+			return '*'
+		}
+		return r
+	}, mobile)
+}
+
+// isMasked reports whether the number is already masked.
+func isMasked(mobile string) bool {
+	return strings.HasPrefix(mobile, "h:") || strings.ContainsRune(mobile, '*')
+}
+
+// MaskDestination returns the comma separated destination with each number masked.
+func (m *Masker) MaskDestination(destination string) string {
+	if m == nil || m.Policy == MaskNone {
+		return destination
+	}
+	mobiles := SplitDestination(destination)
+	for i, mobile := range mobiles {
+		mobiles[i] = m.Mask(mobile)
+	}
+	return strings.Join(mobiles, ",")
+}
+
+// MaskText returns the text with the phone numbers it contains masked.
+func (m *Masker) MaskText(text string) string {
+	if m == nil || m.Policy == MaskNone {
+		return text
+	}
+	return mobilePattern.ReplaceAllStringFunc(text, m.Mask)
+}
+
+// MaskReport returns a copy of the report with the destination masked.
+func (m *Masker) MaskReport(report *ReportMessage) *ReportMessage {
+	if m == nil || m.Policy == MaskNone {
+		return report
+	}
+	masked := *report
+	masked.Destination = m.Mask(report.Destination)
+	return &masked
+}
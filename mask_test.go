@@ -0,0 +1,88 @@
+package every8d
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMasker_Mask(t *testing.T) {
+	tests := []struct {
+		masker *Masker
+		mobile string
+		want   string
+	}{
+		{nil, "0987654321", "0987654321"},
+		{&Masker{Policy: MaskNone}, "0987654321", "0987654321"},
+		{&Masker{Policy: MaskPartial}, "0987654321", "+8869****4321"},
+		{&Masker{Policy: MaskPartial}, "+886912345678", "+8869****5678"},
+		{&Masker{Policy: MaskPartial, KeepPrefix: 3, KeepSuffix: 2}, "+886912345678", "+886*******78"},
+		{&Masker{Policy: MaskPartial}, "12345678", "****5678"},
+		{&Masker{Policy: MaskPartial}, "", ""},
+		{&Masker{Policy: MaskPartial}, "+8869****4321", "+8869****4321"},
+		{&Masker{Policy: MaskFull}, "0987654321", "+************"},
+	}
+
+	for i, test := range tests {
+		if got := test.masker.Mask(test.mobile); got != test.want {
+			t.Errorf("Mask %d. returned %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestMasker_Mask_hash(t *testing.T) {
+	m := &Masker{Policy: MaskHash, Salt: []byte("salt")}
+
+	got := m.Mask("0987654321")
+	if !strings.HasPrefix(got, "h:") || len(got) != 2+maskHashLength {
+		t.Errorf("Mask returned %v, want a hash", got)
+	}
+	if same := m.Mask("+886987654321"); same != got {
+		t.Errorf("Mask returned %v for the same number, want %v", same, got)
+	}
+	if masked := m.Mask(got); masked != got {
+		t.Errorf("Mask returned %v for the hash, want %v", masked, got)
+	}
+	if other := (&Masker{Policy: MaskHash, Salt: []byte("pepper")}).Mask("0987654321"); other == got {
+		t.Errorf("Mask returned %v with another salt, want a different hash", other)
+	}
+}
+
+func TestMasker_MaskText(t *testing.T) {
+	got := DefaultMasker.MaskText("Invalid number 0987654321, or +886912345678.")
+	if want := "Invalid number +8869****4321, or +8869****5678."; got != want {
+		t.Errorf("MaskText returned %v, want %v", got, want)
+	}
+}
+
+func TestParseMaskPolicy(t *testing.T) {
+	for _, policy := range []MaskPolicy{MaskNone, MaskPartial, MaskFull, MaskHash} {
+		got, err := ParseMaskPolicy(strings.ToUpper(policy.String()))
+		if err != nil {
+			t.Errorf("ParseMaskPolicy returned unexpected error: %v", err)
+		}
+		if got != policy {
+			t.Errorf("ParseMaskPolicy returned %v, want %v", got, policy)
+		}
+	}
+	if _, err := ParseMaskPolicy("bogus"); err == nil {
+		t.Error("Expected error to be returned.")
+	}
+}
+
+func TestErrorResponse_Error_masked(t *testing.T) {
+	u, _ := url.Parse("https://oms.every8d.com/API21/HTTP/sendSMS.ashx?PWD=secret&DEST=0987654321")
+	err := &ErrorResponse{
+		Response:  &http.Response{Request: &http.Request{Method: "POST", URL: u}, StatusCode: 200},
+		ErrorCode: -99,
+		Message:   "0987654321 error",
+	}
+
+	got := err.Error()
+	for _, leak := range []string{"secret", "0987654321", "987654321"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("Error returned %v, which contains %v", got, leak)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package every8d
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the measurements of the client and of the webhook,
+// see Client.Metrics and WebhookHandler.Metrics.
+type Metrics interface {
+	// ObserveRequest is called after each API request to the endpoint, e.g.
+	// "API21/HTTP/sendSMS.ashx", with the error it returned, if any.
+	ObserveRequest(endpoint string, latency time.Duration, err error)
+
+	// ObserveSend is called with the response of each sent message.
+	ObserveSend(resp *SendResponse)
+
+	// ObserveCredit is called with the account credit, retrieved or returned by a send.
+	ObserveCredit(credit float64)
+
+	// ObserveReport is called with each report received by the webhook.
+	ObserveReport(report *ReportMessage)
+}
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the request latency histogram.
+var DefaultLatencyBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// PrometheusMetrics keeps the metrics in memory and writes them in the Prometheus
+// text exposition format. It serves them over HTTP, e.g. on /metrics:
+//
+//	metrics := every8d.NewPrometheusMetrics()
+//	client.Metrics = metrics
+//	http.Handle("/metrics", metrics)
+type PrometheusMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu            sync.Mutex
+	requests      map[string]float64
+	requestErrors map[[2]string]float64
+	latencies     map[string]*histogram
+	sent          float64
+	unsent        float64
+	cost          float64
+	credit        float64
+	creditSet     bool
+	callbacks     map[[2]string]float64
+}
+
+// NewPrometheusMetrics returns a new PrometheusMetrics whose metric names are prefixed with "every8d_".
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return NewPrometheusMetricsWith("every8d", DefaultLatencyBuckets)
+}
+
+// NewPrometheusMetricsWith returns a new PrometheusMetrics with the metric name prefix
+// and the latency histogram buckets, in seconds.
+func NewPrometheusMetricsWith(namespace string, buckets []float64) *PrometheusMetrics {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &PrometheusMetrics{
+		namespace:     namespace,
+		buckets:       buckets,
+		requests:      make(map[string]float64),
+		requestErrors: make(map[[2]string]float64),
+		latencies:     make(map[string]*histogram),
+		callbacks:     make(map[[2]string]float64),
+	}
+}
+
+// ObserveRequest implements the Metrics interface. The errors are counted by the
+// StatusCode of the *ErrorResponse, or as "unknown".
+func (m *PrometheusMetrics) ObserveRequest(endpoint string, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[endpoint]++
+
+	h, ok := m.latencies[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latencies[endpoint] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+
+	if err != nil {
+		status := "unknown"
+		if e, ok := err.(*ErrorResponse); ok {
+			status = strconv.Itoa(int(e.ErrorCode))
+		}
+		m.requestErrors[[2]string{endpoint, status}]++
+	}
+}
+
+// ObserveSend implements the Metrics interface.
+func (m *PrometheusMetrics) ObserveSend(resp *SendResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent += float64(resp.Sent)
+	m.unsent += float64(resp.Unsent)
+	m.cost += resp.Cost
+}
+
+// ObserveCredit implements the Metrics interface.
+func (m *PrometheusMetrics) ObserveCredit(credit float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.credit, m.creditSet = credit, true
+}
+
+// ObserveReport implements the Metrics interface.
+func (m *PrometheusMetrics) ObserveReport(report *ReportMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.callbacks[[2]string{strconv.Itoa(int(report.StatusCode)), report.StatusCode.Category().String()}]++
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	name := func(s string) string {
+		if m.namespace == "" {
+			return s
+		}
+		return m.namespace + "_" + s
+	}
+
+	family := func(metric, typ, help string) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", name(metric), help, name(metric), typ)
+	}
+	sample := func(metric string, labels []string, value float64) {
+		fmt.Fprintf(cw, "%s%s %s\n", name(metric), formatLabels(labels), formatValue(value))
+	}
+
+	family("requests_total", "counter", "API requests by endpoint.")
+	for _, endpoint := range sortedKeys(m.requests) {
+		sample("requests_total", []string{"endpoint", endpoint}, m.requests[endpoint])
+	}
+
+	family("request_errors_total", "counter", "API request errors by endpoint and status code.")
+	for _, key := range sortedPairs(m.requestErrors) {
+		sample("request_errors_total", []string{"endpoint", key[0], "status", key[1]}, m.requestErrors[key])
+	}
+
+	family("request_duration_seconds", "histogram", "API request latency by endpoint.")
+	endpoints := make([]string, 0, len(m.latencies))
+	for endpoint := range m.latencies {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		h := m.latencies[endpoint]
+		for i, bound := range m.buckets {
+			sample("request_duration_seconds_bucket", []string{"endpoint", endpoint, "le", formatValue(bound)}, float64(h.counts[i]))
+		}
+		sample("request_duration_seconds_bucket", []string{"endpoint", endpoint, "le", "+Inf"}, float64(h.count))
+		sample("request_duration_seconds_sum", []string{"endpoint", endpoint}, h.sum)
+		sample("request_duration_seconds_count", []string{"endpoint", endpoint}, float64(h.count))
+	}
+
+	family("messages_sent_total", "counter", "Messages sent.")
+	sample("messages_sent_total", nil, m.sent)
+	family("messages_unsent_total", "counter", "Messages not sent, with no credit charged.")
+	sample("messages_unsent_total", nil, m.unsent)
+	family("cost_points_total", "counter", "Points spent sending messages.")
+	sample("cost_points_total", nil, m.cost)
+
+	if m.creditSet {
+		family("credit", "gauge", "Account credit.")
+		sample("credit", nil, m.credit)
+	}
+
+	family("callbacks_total", "counter", "Callbacks received by status code.")
+	for _, key := range sortedPairs(m.callbacks) {
+		sample("callbacks_total", []string{"status", key[0], "category", key[1]}, m.callbacks[key])
+	}
+
+	if err := cw.w.(*bufio.Writer).Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP implements the http.Handler interface, writing the metrics.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats the label name and value pairs.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(m map[[2]string]float64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
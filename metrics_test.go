@@ -0,0 +1,144 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics_client(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	metrics := NewPrometheusMetrics()
+	client.Metrics = metrics
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("DEST") == "" {
+			fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+			return
+		}
+		fmt.Fprint(w, "87.00,2,2.5,1,00000000-0000-0000-0000-000000000000")
+	})
+	mux.HandleFunc("/API21/HTTP/getCredit.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "85")
+	})
+
+	ctx := context.Background()
+	if _, err := client.Send(ctx, Message{Content: "Hello", Destination: "+886987654321"}); err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if _, err := client.Send(ctx, Message{Content: "Hello"}); err == nil {
+		t.Fatal("Expected error to be returned.")
+	}
+	if _, err := client.GetCredit(ctx); err != nil {
+		t.Fatalf("GetCredit returned unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo returned unexpected error: %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		`every8d_requests_total{endpoint="API21/HTTP/sendSMS.ashx"} 2`,
+		`every8d_requests_total{endpoint="API21/HTTP/getCredit.ashx"} 1`,
+		`every8d_request_errors_total{endpoint="API21/HTTP/sendSMS.ashx",status="-99"} 1`,
+		`every8d_request_duration_seconds_bucket{endpoint="API21/HTTP/sendSMS.ashx",le="+Inf"} 2`,
+		`every8d_request_duration_seconds_count{endpoint="API21/HTTP/getCredit.ashx"} 1`,
+		"every8d_messages_sent_total 2",
+		"every8d_messages_unsent_total 1",
+		"every8d_cost_points_total 2.5",
+		"every8d_credit 85",
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("WriteTo output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPrometheusMetrics_WriteTo(t *testing.T) {
+	metrics := NewPrometheusMetricsWith("test", []float64{1, 0.1})
+	metrics.ObserveRequest(`a"b`, 50*time.Millisecond, nil)
+	metrics.ObserveRequest(`a"b`, 500*time.Millisecond, fmt.Errorf("timeout"))
+	metrics.ObserveReport(createReportMessage())
+
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+
+	want := `# HELP test_requests_total API requests by endpoint.
+# TYPE test_requests_total counter
+test_requests_total{endpoint="a\"b"} 2
+# HELP test_request_errors_total API request errors by endpoint and status code.
+# TYPE test_request_errors_total counter
+test_request_errors_total{endpoint="a\"b",status="unknown"} 1
+# HELP test_request_duration_seconds API request latency by endpoint.
+# TYPE test_request_duration_seconds histogram
+test_request_duration_seconds_bucket{endpoint="a\"b",le="0.1"} 1
+test_request_duration_seconds_bucket{endpoint="a\"b",le="1"} 2
+test_request_duration_seconds_bucket{endpoint="a\"b",le="+Inf"} 2
+test_request_duration_seconds_sum{endpoint="a\"b"} 0.55
+test_request_duration_seconds_count{endpoint="a\"b"} 2
+# HELP test_messages_sent_total Messages sent.
+# TYPE test_messages_sent_total counter
+test_messages_sent_total 0
+# HELP test_messages_unsent_total Messages not sent, with no credit charged.
+# TYPE test_messages_unsent_total counter
+test_messages_unsent_total 0
+# HELP test_cost_points_total Points spent sending messages.
+# TYPE test_cost_points_total counter
+test_cost_points_total 0
+# HELP test_callbacks_total Callbacks received by status code.
+# TYPE test_callbacks_total counter
+test_callbacks_total{status="100",category="success"} 1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTo returned\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrometheusMetrics_webhook(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	h := &WebhookHandler{Metrics: metrics}
+
+	reply := createReportMessage()
+	reply.StatusCode = StatusReplayContent
+	for _, report := range []*ReportMessage{createReportMessage(), createReportMessage(), reply} {
+		h.ServeHTTP(httptest.NewRecorder(), newCallbackRequest(report))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got, want := w.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	for _, want := range []string{
+		`every8d_callbacks_total{status="100",category="success"} 2`,
+		fmt.Sprintf(`every8d_callbacks_total{status="%d",category="reply"} 1`, StatusReplayContent),
+	} {
+		if !strings.Contains(w.Body.String(), want+"\n") {
+			t.Errorf("ServeHTTP body does not contain %q:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestPrometheusMetrics_webhookDeduplicator(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	h := &WebhookHandler{Metrics: metrics, Deduplicator: &Deduplicator{}}
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newCallbackRequest(createReportMessage()))
+	}
+
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+	if want := `every8d_callbacks_total{status="100",category="success"} 1`; !strings.Contains(buf.String(), want+"\n") {
+		t.Errorf("WriteTo output does not contain %q:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,39 @@
+package every8d
+
+import (
+	"strings"
+)
+
+// NormalizeMobile converts a Taiwan mobile number to the international format used
+// in the EVERY8D reports, e.g. "0912-345-678" and "886912345678" become "+886912345678".
+// Other numbers are returned without separators.
+func NormalizeMobile(mobile string) string {
+	mobile = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(mobile))
+
+	switch {
+	case strings.HasPrefix(mobile, "09") && len(mobile) == 10:
+		return "+886" + mobile[1:]
+	case strings.HasPrefix(mobile, "8869"):
+		return "+" + mobile
+	case strings.HasPrefix(mobile, "+88609"):
+		return "+886" + mobile[5:]
+	}
+	return mobile
+}
+
+// SplitDestination splits a comma separated destination into normalized mobile numbers.
+func SplitDestination(destination string) []string {
+	var mobiles []string
+	for _, mobile := range strings.Split(destination, ",") {
+		if mobile = NormalizeMobile(mobile); mobile != "" {
+			mobiles = append(mobiles, mobile)
+		}
+	}
+	return mobiles
+}
@@ -0,0 +1,36 @@
+package every8d
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMobile(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"0912345678", "+886912345678"},
+		{"0912-345-678", "+886912345678"},
+		{" 0912 345 678 ", "+886912345678"},
+		{"886912345678", "+886912345678"},
+		{"+886912345678", "+886912345678"},
+		{"+8860912345678", "+886912345678"},
+		{"+81312345678", "+81312345678"},
+		{"(02)12345678", "0212345678"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeMobile(tt.in); got != tt.want {
+			t.Errorf("NormalizeMobile(%q) returned %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDestination(t *testing.T) {
+	got := SplitDestination("0912345678, +886922333444,,")
+	want := []string{"+886912345678", "+886922333444"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitDestination returned %v, want %v", got, want)
+	}
+}
@@ -1,10 +1,60 @@
 package every8d
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-querystring/query"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// reportTimeLayouts are the layouts accepted for the report time.
+var reportTimeLayouts = []string{
+	"20060102150405",
+	"2006/01/02 15:04:05",
+}
+
+// taipei is the time zone of the EVERY8D platform. Taiwan does not observe daylight saving time.
+var taipei = time.FixedZone("Asia/Taipei", 8*60*60)
+
+// Errors returned by ParseReportMessage, wrapped in a *ReportFieldError.
+var (
+	ErrMissingReportField = errors.New("missing report field")
+	ErrInvalidReportField = errors.New("invalid report field")
 )
 
+// ReportFieldError reports a missing or invalid field of an EVERY8D callback request.
+type ReportFieldError struct {
+	// Field name, e.g. "STATUS".
+	Field string
+
+	// Field value.
+	Value string
+
+	// ErrMissingReportField or ErrInvalidReportField.
+	Err error
+}
+
+func (e *ReportFieldError) Error() string {
+	if e.Err == ErrMissingReportField {
+		return fmt.Sprintf("%v: %s", e.Err, e.Field)
+	}
+	return fmt.Sprintf("%v: %s=%q", e.Err, e.Field, e.Value)
+}
+
+// Unwrap returns the underlying error.
+func (e *ReportFieldError) Unwrap() error {
+	return e.Err
+}
+
 // ReportMessage represents sending report or reply message.
 type ReportMessage struct {
 	// Batch ID.
@@ -17,6 +67,9 @@ type ReportMessage struct {
 	// Report time.
 	ReportTime string `url:"RT"`
 
+	// Report time parsed in Asia/Taipei time.
+	ReportedAt time.Time `url:"-"`
+
 	// Sending status.
 	StatusCode StatusCode `url:"STATUS"`
 
@@ -28,20 +81,132 @@ type ReportMessage struct {
 }
 
 // ParseReportMessage parses an incoming EVERY8D callback request and return the ReportMessage.
+//
+// The fields are read from the query string of GET requests and from the
+// application/x-www-form-urlencoded body of POST requests. The reply message is
+// decoded from Big5 when it is not valid UTF-8 or the request declares a Big5 charset.
+//
+// A missing or invalid field is reported as a *ReportFieldError.
 func ParseReportMessage(r *http.Request) (*ReportMessage, error) {
-	values := r.URL.Query()
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	values := r.Form
+
+	required := func(field string) (string, error) {
+		value := strings.TrimSpace(values.Get(field))
+		if value == "" {
+			return "", &ReportFieldError{Field: field, Err: ErrMissingReportField}
+		}
+		return value, nil
+	}
 
-	code, err := strconv.Atoi(values.Get("STATUS"))
+	batchID, err := required("BatchID")
+	if err != nil {
+		return nil, err
+	}
+	destination, err := required("RM")
+	if err != nil {
+		return nil, err
+	}
+	reportTime, err := required("RT")
+	if err != nil {
+		return nil, err
+	}
+	status, err := required("STATUS")
 	if err != nil {
 		return nil, err
 	}
 
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, &ReportFieldError{Field: "STATUS", Value: status, Err: ErrInvalidReportField}
+	}
+	reportedAt, err := parseReportTime(reportTime)
+	if err != nil {
+		return nil, &ReportFieldError{Field: "RT", Value: reportTime, Err: ErrInvalidReportField}
+	}
+	replyMessage, err := decodeReplyMessage(values.Get("SM"), r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, &ReportFieldError{Field: "SM", Value: values.Get("SM"), Err: ErrInvalidReportField}
+	}
+
 	return &ReportMessage{
-		BatchID:      values.Get("BatchID"),
-		Destination:  values.Get("RM"),
-		ReportTime:   values.Get("RT"),
+		BatchID:      batchID,
+		Destination:  destination,
+		ReportTime:   reportTime,
+		ReportedAt:   reportedAt,
 		StatusCode:   StatusCode(code),
-		ReplyMessage: values.Get("SM"),
+		ReplyMessage: replyMessage,
 		MessageNo:    values.Get("MR"),
 	}, nil
 }
+
+// DeliverReport sends the report to the callback URL as an EVERY8D GET callback request.
+// If httpClient is nil, http.DefaultClient is used. A response other than 2xx is an error.
+func DeliverReport(ctx context.Context, httpClient *http.Client, callbackURL string, report *ReportMessage) error {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return err
+	}
+	values, err := query.Values(report)
+	if err != nil {
+		return err
+	}
+	params := u.Query()
+	for k, v := range values {
+		params[k] = v
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func parseReportTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range reportTimeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, taipei); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// decodeReplyMessage converts the reply message to UTF-8.
+func decodeReplyMessage(value, contentType string) (string, error) {
+	big5 := !utf8.ValidString(value)
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if charset := strings.ToLower(params["charset"]); charset == "big5" || charset == "big-5" {
+			big5 = true
+		}
+	}
+	if !big5 {
+		return value, nil
+	}
+
+	decoded, err := traditionalchinese.Big5.NewDecoder().String(value)
+	if err != nil {
+		return "", err
+	}
+	return decoded, nil
+}
@@ -2,12 +2,17 @@ package every8d
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-querystring/query"
+	"golang.org/x/text/encoding/traditionalchinese"
 )
 
 func createReportMessage() *ReportMessage {
@@ -15,6 +20,7 @@ func createReportMessage() *ReportMessage {
 		BatchID:      "00000000-0000-0000-0000-000000000000",
 		Destination:  "+886987654321",
 		ReportTime:   "20090210120000",
+		ReportedAt:   time.Date(2009, 2, 10, 12, 0, 0, 0, taipei),
 		StatusCode:   StatusCode(100),
 		ReplyMessage: "Reply, Hello",
 		MessageNo:    "001",
@@ -70,3 +76,129 @@ func TestParseReportMessage_error(t *testing.T) {
 	req, _ := client.NewRequest(http.MethodGet, u.String(), nil)
 	client.Do(context.Background(), req, nil, nil)
 }
+
+func TestParseReportMessage_postForm(t *testing.T) {
+	want := createReportMessage()
+	q, _ := query.Values(want)
+
+	r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(q.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	got, err := ParseReportMessage(r)
+	if err != nil {
+		t.Fatalf("ParseReportMessage returned unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Report message got %v, want %v", got, want)
+	}
+}
+
+func TestParseReportMessage_big5(t *testing.T) {
+	big5, _ := traditionalchinese.Big5.NewEncoder().String("你好，世界")
+
+	tests := []struct {
+		contentType string
+		sm          string
+	}{
+		{"", big5},
+		{"application/x-www-form-urlencoded; charset=big5", big5},
+		{"application/x-www-form-urlencoded; charset=utf-8", "你好，世界"},
+	}
+
+	for i, tt := range tests {
+		q, _ := query.Values(createReportMessage())
+		q.Set("STATUS", "999")
+		q.Set("SM", tt.sm)
+
+		r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(q.Encode()))
+		r.Header.Set("Content-Type", tt.contentType)
+		if tt.contentType == "" {
+			r = httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil)
+		}
+
+		got, err := ParseReportMessage(r)
+		if err != nil {
+			t.Fatalf("ParseReportMessage %d. returned unexpected error %v", i, err)
+		}
+		if want := "你好，世界"; got.ReplyMessage != want {
+			t.Errorf("ParseReportMessage %d. returned SM %q, want %q", i, got.ReplyMessage, want)
+		}
+	}
+}
+
+func TestParseReportMessage_fieldError(t *testing.T) {
+	tests := []struct {
+		field, value string
+		want         *ReportFieldError
+	}{
+		{"BatchID", "", &ReportFieldError{Field: "BatchID", Err: ErrMissingReportField}},
+		{"RM", " ", &ReportFieldError{Field: "RM", Err: ErrMissingReportField}},
+		{"RT", "", &ReportFieldError{Field: "RT", Err: ErrMissingReportField}},
+		{"STATUS", "", &ReportFieldError{Field: "STATUS", Err: ErrMissingReportField}},
+		{"STATUS", "Invalid", &ReportFieldError{Field: "STATUS", Value: "Invalid", Err: ErrInvalidReportField}},
+		{"RT", "2009-02-10", &ReportFieldError{Field: "RT", Value: "2009-02-10", Err: ErrInvalidReportField}},
+	}
+
+	for _, tt := range tests {
+		q, _ := query.Values(createReportMessage())
+		q.Set(tt.field, tt.value)
+
+		_, err := ParseReportMessage(httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil))
+		if !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("ParseReportMessage returned error %#v, want %#v", err, tt.want)
+		}
+		if !errors.Is(err, tt.want.Err) {
+			t.Errorf("ParseReportMessage returned error %v, want %v", err, tt.want.Err)
+		}
+	}
+}
+
+func TestParseReportMessage_reportTimeLayout(t *testing.T) {
+	q, _ := query.Values(createReportMessage())
+	q.Set("RT", "2009/02/10 12:00:00")
+
+	got, err := ParseReportMessage(httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil))
+	if err != nil {
+		t.Fatalf("ParseReportMessage returned unexpected error %v", err)
+	}
+	if want := time.Date(2009, 2, 10, 4, 0, 0, 0, time.UTC); !got.ReportedAt.Equal(want) {
+		t.Errorf("ParseReportMessage returned ReportedAt %v, want %v", got.ReportedAt, want)
+	}
+}
+
+// reportValues returns the report encoded as the query parameters of a callback request.
+func reportValues(report *ReportMessage) url.Values {
+	values, _ := query.Values(report)
+	return values
+}
+
+func TestDeliverReport(t *testing.T) {
+	want := createReportMessage()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("token"); got != "secret" {
+			t.Errorf("Request token is %v, want secret", got)
+		}
+		got, err := ParseReportMessage(r)
+		if err != nil {
+			t.Errorf("ParseReportMessage returned unexpected error %v", err)
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Report message got %v, want %v", got, want)
+		}
+		if got.BatchID == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	if err := DeliverReport(context.Background(), nil, server.URL+"/callback?token=secret", want); err != nil {
+		t.Errorf("DeliverReport returned unexpected error: %v", err)
+	}
+
+	want.BatchID = "fail"
+	if err := DeliverReport(context.Background(), nil, server.URL+"/callback?token=secret", want); err == nil {
+		t.Error("Expected error to be returned")
+	}
+}
@@ -0,0 +1,50 @@
+package every8d
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	gsm7SingleLength = 160
+	gsm7PartLength   = 153
+	ucs2SingleLength = 70
+	ucs2PartLength   = 67
+)
+
+// gsm7Basic and gsm7Extension are the characters of the GSM 03.38 default alphabet
+// and of its extension table, which take two septets.
+const (
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extension = "^{}\\[~]|€\f"
+)
+
+// SegmentCount returns the number of SMS segments the content is sent in, at least 1.
+// Contents of the GSM 7-bit alphabet fit 160 characters in a single segment, or 153
+// per segment of a concatenated message; other contents, e.g. Chinese, fit 70 and 67.
+func SegmentCount(content string) int {
+	septets, gsm7 := 0, true
+	for _, r := range content {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extension, r):
+			septets += 2
+		default:
+			gsm7 = false
+		}
+		if !gsm7 {
+			break
+		}
+	}
+
+	length, single, part := septets, gsm7SingleLength, gsm7PartLength
+	if !gsm7 {
+		length, single, part = len(utf16.Encode([]rune(content))), ucs2SingleLength, ucs2PartLength
+	}
+	if length <= single {
+		return 1
+	}
+	return (length + part - 1) / part
+}
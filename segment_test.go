@@ -0,0 +1,32 @@
+package every8d
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentCount(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 1},
+		{"Hello", 1},
+		{strings.Repeat("a", 160), 1},
+		{strings.Repeat("a", 161), 2},
+		{strings.Repeat("a", 306), 2},
+		{strings.Repeat("a", 307), 3},
+		{strings.Repeat("€", 80), 1},
+		{strings.Repeat("€", 81), 2},
+		{"Hello, 世界", 1},
+		{strings.Repeat("世", 70), 1},
+		{strings.Repeat("世", 71), 2},
+		{strings.Repeat("世", 135), 3},
+	}
+
+	for i, test := range tests {
+		if got := SegmentCount(test.content); got != test.want {
+			t.Errorf("SegmentCount %d. returned %v, want %v", i, got, test.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package every8d
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"io"
 	"strconv"
 
@@ -53,11 +54,37 @@ type SendResponse struct {
 
 	// Batch ID. e.g. 220478cc-8506-49b2-93b7-2505f651c12e
 	BatchID string
+
+	// Numbers removed from the destination by the Client.Suppression list.
+	Suppressed []string
 }
 
 // Send sends an SMS.
+//
+// The numbers of the Client.Suppression list are removed from the destination.
+// If no number is left, nothing is sent and ErrAllSuppressed is returned.
+//
+// The message is recorded in the Client.Correlation store and its cost in the
+// Client.Ledger. A failure to record it is reported to Client.OnRecordError rather
+// than returned, since the message was sent and must not be sent again.
 func (c *Client) Send(ctx context.Context, message Message) (*SendResponse, error) {
-	return c.send(ctx, "API21/HTTP/sendSMS.ashx", message)
+	destination, suppressed, err := c.suppress(ctx, message.Destination)
+	if err != nil {
+		return nil, err
+	}
+	if len(suppressed) > 0 && destination == "" {
+		return &SendResponse{Suppressed: suppressed}, ErrAllSuppressed
+	}
+	message.Destination = destination
+
+	resp, err := c.send(ctx, "API21/HTTP/sendSMS.ashx", message.Destination, message)
+	if err != nil {
+		return nil, err
+	}
+	resp.Suppressed = suppressed
+
+	c.record(ctx, NewSendRecord(message, resp), false, resp)
+	return resp, nil
 }
 
 // MMS represents an MMS object.
@@ -92,11 +119,47 @@ type MMS struct {
 }
 
 // SendMMS sends a MMS.
+//
+// The numbers of the Client.Suppression list are removed from the destination.
+// If no number is left, nothing is sent and ErrAllSuppressed is returned.
+//
+// The message is recorded in the Client.Correlation store and its cost in the
+// Client.Ledger. A failure to record it is reported to Client.OnRecordError rather
+// than returned, since the message was sent and must not be sent again.
 func (c *Client) SendMMS(ctx context.Context, message MMS) (*SendResponse, error) {
-	return c.send(ctx, "API21/HTTP/MMS/sendMMS.ashx", message)
+	destination, suppressed, err := c.suppress(ctx, message.Destination)
+	if err != nil {
+		return nil, err
+	}
+	if len(suppressed) > 0 && destination == "" {
+		return &SendResponse{Suppressed: suppressed}, ErrAllSuppressed
+	}
+	message.Destination = destination
+
+	resp, err := c.send(ctx, "API21/HTTP/MMS/sendMMS.ashx", message.Destination, message)
+	if err != nil {
+		return nil, err
+	}
+	resp.Suppressed = suppressed
+
+	c.record(ctx, NewMMSSendRecord(message, resp), true, resp)
+	return resp, nil
 }
 
-func (c *Client) send(ctx context.Context, urlStr string, message interface{}) (*SendResponse, error) {
+// record records the sent message in the Client.Correlation store and its cost in the
+// Client.Ledger, the cost even if the message fails to be correlated.
+func (c *Client) record(ctx context.Context, record *SendRecord, mms bool, resp *SendResponse) {
+	err := errors.Join(c.correlate(ctx, record), c.account(ctx, mms, resp))
+	if err == nil {
+		return
+	}
+	c.logRecordError(ctx, resp, err)
+	if c.OnRecordError != nil {
+		c.OnRecordError(ctx, resp, err)
+	}
+}
+
+func (c *Client) send(ctx context.Context, urlStr, destination string, message interface{}) (*SendResponse, error) {
 	f, _ := form.NewEncoder().Encode(message)
 
 	req, err := c.NewFormRequest(urlStr, f)
@@ -117,11 +180,11 @@ func (c *Client) send(ctx context.Context, urlStr string, message interface{}) (
 		unsent, _ := strconv.Atoi(record[3])
 
 		*v.(*SendResponse) = SendResponse{
-			credit,
-			sent,
-			cost,
-			unsent,
-			record[4],
+			Credit:  credit,
+			Sent:    sent,
+			Cost:    cost,
+			Unsent:  unsent,
+			BatchID: record[4],
 		}
 
 		return nil
@@ -132,6 +195,16 @@ func (c *Client) send(ctx context.Context, urlStr string, message interface{}) (
 	if err != nil {
 		return nil, err
 	}
+	if c.Metrics != nil {
+		c.Metrics.ObserveSend(result)
+		if result.Credit >= 0 {
+			c.Metrics.ObserveCredit(result.Credit)
+		}
+	}
+	if c.CreditMonitor != nil {
+		c.CreditMonitor.Update(result.Credit)
+	}
+	c.logSend(ctx, urlStr, destination, result)
 
 	return result, nil
 }
@@ -0,0 +1,162 @@
+package every8d
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewDeliveryReport returns a sending report of the status, reported now.
+func NewDeliveryReport(batchID, mobile string, status StatusCode) *ReportMessage {
+	now := time.Now().In(taipei).Truncate(time.Second)
+	return &ReportMessage{
+		BatchID:     batchID,
+		Destination: mobile,
+		ReportTime:  now.Format(reportTimeLayouts[0]),
+		ReportedAt:  now,
+		StatusCode:  status,
+	}
+}
+
+// NewReplyReport returns a reply message of the text, reported now.
+func NewReplyReport(batchID, mobile, text string) *ReportMessage {
+	report := NewDeliveryReport(batchID, mobile, StatusReplayContent)
+	report.ReplyMessage = text
+	return report
+}
+
+// ReportSequence returns the reports of a message delivered to the number: sent,
+// then received, then the reply if text is not empty.
+func ReportSequence(batchID, mobile, text string) []*ReportMessage {
+	reports := []*ReportMessage{
+		NewDeliveryReport(batchID, mobile, StatusSent),
+		NewDeliveryReport(batchID, mobile, StatusMessageReceived),
+	}
+	if text != "" {
+		reports = append(reports, NewReplyReport(batchID, mobile, text))
+	}
+	return reports
+}
+
+// NewBatchID returns a random batch ID in the format of the EVERY8D ones.
+func NewBatchID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Simulator fires EVERY8D callback requests at a webhook URL, to test an integration
+// end-to-end without sending real messages.
+type Simulator struct {
+	// Callback URL of the webhook.
+	URL string
+
+	// HTTP client used to deliver the reports. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Interval between the reports of a sequence.
+	Interval time.Duration
+
+	// Number of reports delivered in parallel by Load. Defaults to 1.
+	Concurrency int
+}
+
+// NewSimulator returns a new Simulator delivering to the callback URL.
+func NewSimulator(callbackURL string) *Simulator {
+	return &Simulator{URL: callbackURL}
+}
+
+// Deliver delivers the report, see DeliverReport.
+func (s *Simulator) Deliver(ctx context.Context, report *ReportMessage) error {
+	return DeliverReport(ctx, s.Client, s.URL, report)
+}
+
+// Run delivers the reports in order, waiting Interval between them. It stops at the first error.
+func (s *Simulator) Run(ctx context.Context, reports []*ReportMessage) error {
+	for i, report := range reports {
+		if i > 0 && s.Interval > 0 {
+			timer := time.NewTimer(s.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if err := s.Deliver(ctx, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadResult summarizes a load run.
+type LoadResult struct {
+	Delivered int
+	Failed    int
+	Duration  time.Duration
+
+	// First error returned by the webhook, if any.
+	Err error
+}
+
+// Rate returns the delivered reports per second.
+func (r *LoadResult) Rate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Delivered) / r.Duration.Seconds()
+}
+
+// Load delivers n reports built by report, Concurrency at a time. It returns early
+// with the partial result when the context is done.
+func (s *Simulator) Load(ctx context.Context, n int, report func(i int) *ReportMessage) *LoadResult {
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var delivered, failed int64
+	var once sync.Once
+	result := new(LoadResult)
+	start := time.Now()
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := s.Deliver(ctx, report(i)); err != nil {
+					atomic.AddInt64(&failed, 1)
+					once.Do(func() { result.Err = err })
+				} else {
+					atomic.AddInt64(&delivered, 1)
+				}
+			}
+		}()
+	}
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			break loop
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	result.Delivered = int(delivered)
+	result.Failed = int(failed)
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,95 @@
+package every8d
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// recordingWebhook returns a test server parsing the callbacks with a WebhookHandler.
+func recordingWebhook(fail func(report *ReportMessage) bool) (*httptest.Server, func() []*ReportMessage) {
+	var mu sync.Mutex
+	var reports []*ReportMessage
+	handle := func(ctx context.Context, report *ReportMessage) error {
+		if fail != nil && fail(report) {
+			return context.Canceled
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, report)
+		return nil
+	}
+	server := httptest.NewServer(&WebhookHandler{OnDeliveryReport: handle, OnReply: handle})
+	return server, func() []*ReportMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*ReportMessage(nil), reports...)
+	}
+}
+
+func TestSimulator_Run(t *testing.T) {
+	server, received := recordingWebhook(nil)
+	defer server.Close()
+
+	batchID := NewBatchID()
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(batchID) {
+		t.Errorf("NewBatchID returned %q", batchID)
+	}
+
+	sequence := ReportSequence(batchID, "+886987654321", "好的")
+	if err := NewSimulator(server.URL).Run(context.Background(), sequence); err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	got := received()
+	if len(got) != len(sequence) {
+		t.Fatalf("Webhook received %d reports, want %d", len(got), len(sequence))
+	}
+	for i, report := range got {
+		if !reflect.DeepEqual(reportValues(report), reportValues(sequence[i])) || !report.ReportedAt.Equal(sequence[i].ReportedAt) {
+			t.Errorf("Webhook received %+v, want %+v", report, sequence[i])
+		}
+	}
+	if want := []StatusCode{StatusSent, StatusMessageReceived, StatusReplayContent}; got[0].StatusCode != want[0] || got[1].StatusCode != want[1] || got[2].StatusCode != want[2] {
+		t.Errorf("Webhook received statuses %v %v %v, want %v", got[0].StatusCode, got[1].StatusCode, got[2].StatusCode, want)
+	}
+}
+
+func TestSimulator_Load(t *testing.T) {
+	server, received := recordingWebhook(func(report *ReportMessage) bool {
+		return report.StatusCode == StatusMobileNumberNotExist
+	})
+	defer server.Close()
+
+	simulator := &Simulator{URL: server.URL, Concurrency: 4}
+	result := simulator.Load(context.Background(), 20, func(i int) *ReportMessage {
+		if i%5 == 0 {
+			return NewDeliveryReport(NewBatchID(), "+886987654321", StatusMobileNumberNotExist)
+		}
+		return NewDeliveryReport(NewBatchID(), "+886987654321", StatusMessageReceived)
+	})
+
+	if result.Delivered != 16 || result.Failed != 4 {
+		t.Errorf("Load delivered %d and failed %d, want 16 and 4", result.Delivered, result.Failed)
+	}
+	if result.Err == nil {
+		t.Errorf("Load expected error to be returned")
+	}
+	if got := len(received()); got != 16 {
+		t.Errorf("Webhook received %d reports, want 16", got)
+	}
+}
+
+func TestSimulator_Run_error(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	err := NewSimulator(server.URL).Run(context.Background(), ReportSequence(NewBatchID(), "+886987654321", ""))
+	if err == nil {
+		t.Errorf("Run expected error to be returned")
+	}
+}
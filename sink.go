@@ -0,0 +1,387 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSinkRetries    = 3
+	defaultSinkBackoff    = 500 * time.Millisecond
+	defaultSinkMaxBackoff = 30 * time.Second
+)
+
+// ReportEvent is the JSON document forwarded to the sinks.
+type ReportEvent struct {
+	Category   string         `json:"category"`
+	StatusText string         `json:"status_text"`
+	ReceivedAt time.Time      `json:"received_at"`
+	Report     *ReportMessage `json:"report"`
+}
+
+// NewReportEvent returns the event of a report received now.
+func NewReportEvent(report *ReportMessage) *ReportEvent {
+	return &ReportEvent{
+		Category:   report.StatusCode.Category().String(),
+		StatusText: report.StatusCode.Text(),
+		ReceivedAt: time.Now(),
+		Report:     report,
+	}
+}
+
+// Sink receives the reports forwarded by a Fanout.
+type Sink interface {
+	Deliver(ctx context.Context, report *ReportMessage) error
+}
+
+// SinkFunc is an adapter to use an ordinary function as a Sink.
+type SinkFunc func(ctx context.Context, report *ReportMessage) error
+
+// Deliver calls f(ctx, report).
+func (f SinkFunc) Deliver(ctx context.Context, report *ReportMessage) error {
+	return f(ctx, report)
+}
+
+// DeliveryCategories are the categories of the sending reports, i.e. all but CategoryReply.
+var DeliveryCategories = []StatusCategory{
+	CategoryUnknown,
+	CategoryAPIError,
+	CategoryPending,
+	CategorySuccess,
+	CategoryFailure,
+	CategoryCanceled,
+	CategoryTest,
+}
+
+// ErrSinkRejected is wrapped by the errors of the reports a sink rejects, e.g. with
+// a 4xx response, which are not worth retrying.
+var ErrSinkRejected = errors.New("report rejected")
+
+// SinkError reports the failure of a sink.
+type SinkError struct {
+	Sink Sink
+	Err  error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Sink, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// FanoutError reports the sinks a report failed to be forwarded to.
+type FanoutError struct {
+	Errors []*SinkError
+}
+
+func (e *FanoutError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "fanout: " + strings.Join(messages, "; ")
+}
+
+type sinkRoute struct {
+	sink       Sink
+	categories map[StatusCategory]bool
+}
+
+// Fanout forwards the reports to several sinks, selected by the category of the
+// report status. Its Deliver method can be used as WebhookHandler.OnDeliveryReport
+// and WebhookHandler.OnReply:
+//
+//	fanout := new(every8d.Fanout)
+//	fanout.Add(&every8d.HTTPSink{URL: "https://example.com/reports"}, every8d.DeliveryCategories...)
+//	fanout.Add(every8d.NewCommandSink("./on-reply.sh"), every8d.CategoryReply)
+//	handler := &every8d.WebhookHandler{OnDeliveryReport: fanout.Deliver, OnReply: fanout.Deliver}
+//
+// Deliver returns once every sink is done. Use a SinkQueue to forward the reports
+// asynchronously.
+type Fanout struct {
+	mu     sync.RWMutex
+	routes []sinkRoute
+}
+
+// Add adds a sink receiving the reports of the categories, or all reports if none is given.
+func (f *Fanout) Add(sink Sink, categories ...StatusCategory) {
+	route := sinkRoute{sink: sink}
+	if len(categories) > 0 {
+		route.categories = make(map[StatusCategory]bool)
+		for _, category := range categories {
+			route.categories[category] = true
+		}
+	}
+
+	f.mu.Lock()
+	f.routes = append(f.routes, route)
+	f.mu.Unlock()
+}
+
+// Sinks returns the number of sinks.
+func (f *Fanout) Sinks() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.routes)
+}
+
+// Deliver implements the Sink interface. The report is forwarded concurrently to the
+// sinks of its category; the failures are returned as a *FanoutError.
+func (f *Fanout) Deliver(ctx context.Context, report *ReportMessage) error {
+	category := report.StatusCode.Category()
+
+	f.mu.RLock()
+	var sinks []Sink
+	for _, route := range f.routes {
+		if route.categories == nil || route.categories[category] {
+			sinks = append(sinks, route.sink)
+		}
+	}
+	f.mu.RUnlock()
+
+	errs := make([]error, len(sinks))
+	var wg sync.WaitGroup
+	for i, sink := range sinks {
+		wg.Add(1)
+		go func(i int, sink Sink) {
+			defer wg.Done()
+			errs[i] = sink.Deliver(ctx, report)
+		}(i, sink)
+	}
+	wg.Wait()
+
+	var fanoutErr *FanoutError
+	for i, err := range errs {
+		if err != nil {
+			if fanoutErr == nil {
+				fanoutErr = new(FanoutError)
+			}
+			fanoutErr.Errors = append(fanoutErr.Errors, &SinkError{Sink: sinks[i], Err: err})
+		}
+	}
+	if fanoutErr != nil {
+		return fanoutErr
+	}
+	return nil
+}
+
+// Close closes the sinks implementing io.Closer.
+func (f *Fanout) Close() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var firstErr error
+	for _, route := range f.routes {
+		if c, ok := route.sink.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// HTTPSink posts the ReportEvent as JSON to a URL. Network errors, 429 and 5xx
+// responses are retried with exponential backoff; the other responses fail with
+// ErrSinkRejected.
+type HTTPSink struct {
+	URL string
+
+	// Header, if not nil, is added to the requests, e.g. an Authorization header.
+	Header http.Header
+
+	// HTTP client used to post. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Number of retries after the first attempt. Defaults to 3, negative disables retries.
+	MaxRetries int
+
+	// Backoff before the first retry, doubled on each retry up to MaxBackoff.
+	// Defaults to 500 milliseconds and 30 seconds.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (s *HTTPSink) String() string {
+	return s.URL
+}
+
+// Deliver implements the Sink interface.
+func (s *HTTPSink) Deliver(ctx context.Context, report *ReportMessage) error {
+	body, err := json.Marshal(NewReportEvent(report))
+	if err != nil {
+		return err
+	}
+
+	retries := s.MaxRetries
+	if retries == 0 {
+		retries = defaultSinkRetries
+	}
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = defaultSinkBackoff
+	}
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultSinkMaxBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(ctx, body)
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post posts the body once and reports whether a failure is worth retrying.
+func (s *HTTPSink) post(ctx context.Context, body []byte) (bool, error) {
+	httpClient := s.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return true, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		return false, fmt.Errorf("%w: unexpected status code: %d", ErrSinkRejected, resp.StatusCode)
+	}
+	return false, nil
+}
+
+// FileSink appends the ReportEvent to a file as JSON Lines.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileSink opens the file at path for appending, creating it if needed.
+func OpenFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: f}, nil
+}
+
+func (s *FileSink) String() string {
+	return s.file.Name()
+}
+
+// Deliver implements the Sink interface.
+func (s *FileSink) Deliver(_ context.Context, report *ReportMessage) error {
+	line, err := json.Marshal(NewReportEvent(report))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(line)
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// CommandSink runs a local command for each report, with the ReportEvent as JSON on
+// its standard input. A non-zero exit status is an error.
+type CommandSink struct {
+	Name string
+	Args []string
+
+	// Env, if not nil, is added to the environment of the command.
+	Env []string
+
+	// Timeout, if positive, kills the command running longer.
+	Timeout time.Duration
+}
+
+// NewCommandSink returns a new CommandSink running the command.
+func NewCommandSink(name string, args ...string) *CommandSink {
+	return &CommandSink{Name: name, Args: args}
+}
+
+func (s *CommandSink) String() string {
+	return strings.Join(append([]string{s.Name}, s.Args...), " ")
+}
+
+// Deliver implements the Sink interface.
+func (s *CommandSink) Deliver(ctx context.Context, report *ReportMessage) error {
+	input, err := json.Marshal(NewReportEvent(report))
+	if err != nil {
+		return err
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, s.Name, s.Args...)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	if s.Env != nil {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if output = bytes.TrimSpace(output); len(output) > 0 {
+			return fmt.Errorf("%v: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package every8d
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueBackoff    = time.Second
+	defaultQueueMaxBackoff = time.Minute
+	queueCursorPrefix      = "cursor-"
+)
+
+// SinkQueue forwards the reports to the sinks of a Fanout asynchronously. The reports
+// are appended to a Journal, so a callback can be acknowledged as soon as its report is
+// durably queued. Each sink consumes the journal from its own cursor, persisted next to
+// the journal, so a failing sink is retried without holding up the others. The journal
+// files consumed by every sink are removed:
+//
+//	journal, err := every8d.OpenJournal("forward", 0)
+//	queue := every8d.NewSinkQueue(fanout, journal)
+//	go queue.Run(ctx)
+//	handler := &every8d.WebhookHandler{OnDeliveryReport: queue.Deliver, OnReply: queue.Deliver}
+type SinkQueue struct {
+	fanout  *Fanout
+	journal *Journal
+
+	// MaxRetries, if positive, is the number of retries after which a sink skips a
+	// report, and if negative disables the retries. Otherwise a sink retries a report
+	// until it is delivered. A report rejected with ErrSinkRejected is skipped at once.
+	MaxRetries int
+
+	// Backoff before the first retry, doubled on each retry up to MaxBackoff.
+	// Defaults to 1 second and 1 minute.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if not nil, is called with each failed delivery.
+	OnError func(err *SinkError, entry *JournalEntry)
+
+	mu      sync.Mutex
+	wake    []chan struct{}
+	offsets []uint64
+}
+
+// NewSinkQueue returns a new SinkQueue forwarding the reports queued in the journal to
+// the sinks of the fanout. The journal should not be shared with a WebhookHandler.
+func NewSinkQueue(fanout *Fanout, journal *Journal) *SinkQueue {
+	return &SinkQueue{fanout: fanout, journal: journal}
+}
+
+// Deliver implements the Sink interface. It returns once the report is durably queued.
+func (q *SinkQueue) Deliver(_ context.Context, report *ReportMessage) error {
+	if _, err := q.journal.Append(report); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, wake := range q.wake {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run forwards the queued reports to the sinks until ctx is done, or a cursor fails to
+// be persisted. The sinks added to the fanout after Run is called are ignored.
+func (q *SinkQueue) Run(ctx context.Context) error {
+	q.fanout.mu.RLock()
+	routes := append([]sinkRoute(nil), q.fanout.routes...)
+	q.fanout.mu.RUnlock()
+
+	q.mu.Lock()
+	q.wake = make([]chan struct{}, len(routes))
+	q.offsets = make([]uint64, len(routes))
+	for i := range routes {
+		q.wake[i] = make(chan struct{}, 1)
+	}
+	q.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ids := make(map[string]int)
+	errs := make(chan error, len(routes))
+	for i, route := range routes {
+		id := sinkID(route.sink)
+		if n := ids[id]; n > 0 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		ids[sinkID(route.sink)]++
+
+		go func(i int, route sinkRoute, cursor string) {
+			errs <- q.consume(ctx, i, route, cursor)
+		}(i, route, filepath.Join(q.journal.Dir(), queueCursorPrefix+id))
+	}
+
+	var firstErr error
+	for range routes {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	return firstErr
+}
+
+// consume forwards the reports of the journal to the sink of the route, from the offset
+// persisted in the cursor file.
+func (q *SinkQueue) consume(ctx context.Context, i int, route sinkRoute, cursor string) error {
+	offset, err := readCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err := ReplayJournal(ctx, q.journal.Dir(), &ReplayOptions{Offset: offset}, func(ctx context.Context, entry *JournalEntry) error {
+			if route.categories == nil || route.categories[entry.Report.StatusCode.Category()] {
+				if err := q.deliver(ctx, route.sink, entry); err != nil {
+					return err
+				}
+			}
+			offset = entry.Offset + 1
+			return writeCursor(cursor, offset)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		q.prune(i, offset)
+
+		q.mu.Lock()
+		wake := q.wake[i]
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		}
+	}
+}
+
+// deliver delivers the entry to the sink, retrying the failures with exponential backoff.
+func (q *SinkQueue) deliver(ctx context.Context, sink Sink, entry *JournalEntry) error {
+	backoff := q.Backoff
+	if backoff <= 0 {
+		backoff = defaultQueueBackoff
+	}
+	maxBackoff := q.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultQueueMaxBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := sink.Deliver(ctx, entry.Report)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if q.OnError != nil {
+			q.OnError(&SinkError{Sink: sink, Err: err}, entry)
+		}
+		if errors.Is(err, ErrSinkRejected) || q.MaxRetries != 0 && attempt >= q.MaxRetries {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// prune records the offset reached by the sink i, and removes the journal files
+// consumed by every sink.
+func (q *SinkQueue) prune(i int, offset uint64) {
+	q.mu.Lock()
+	q.offsets[i] = offset
+	min := offset
+	for _, o := range q.offsets {
+		if o < min {
+			min = o
+		}
+	}
+	q.mu.Unlock()
+
+	q.journal.removeBefore(min)
+}
+
+// sinkID returns the identifier of the cursor of the sink, made of its type and String
+// form, so it is stable across restarts.
+func sinkID(sink Sink) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T %v", sink, sink)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readCursor returns the offset persisted at path, zero if there is none.
+func readCursor(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// writeCursor persists the offset at path, replacing the file atomically.
+func writeCursor(path string, offset uint64) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(offset, 10)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,210 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records the message numbers it receives, failing while fail is set.
+type recordingSink struct {
+	name string
+
+	mu   sync.Mutex
+	fail bool
+	got  []string
+	recv chan struct{}
+}
+
+func newRecordingSink(name string, fail bool) *recordingSink {
+	return &recordingSink{name: name, fail: fail, recv: make(chan struct{}, 100)}
+}
+
+func (s *recordingSink) String() string {
+	return s.name
+}
+
+func (s *recordingSink) Deliver(_ context.Context, report *ReportMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return errors.New("failure")
+	}
+	s.got = append(s.got, report.MessageNo)
+	s.recv <- struct{}{}
+	return nil
+}
+
+func (s *recordingSink) setFail(fail bool) {
+	s.mu.Lock()
+	s.fail = fail
+	s.mu.Unlock()
+}
+
+func (s *recordingSink) wait(t *testing.T, n int) []string {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.recv:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Sink %s received %d reports, want %d", s.name, i, n)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.got...)
+}
+
+func TestSinkQueue(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queue")
+	journal, err := OpenJournal(dir, 1)
+	if err != nil {
+		t.Fatalf("OpenJournal returned unexpected error: %v", err)
+	}
+	defer journal.Close()
+
+	good := newRecordingSink("good", false)
+	bad := newRecordingSink("bad", true)
+	fanout := new(Fanout)
+	fanout.Add(good)
+	fanout.Add(bad)
+
+	failed := make(chan struct{}, 100)
+	queue := NewSinkQueue(fanout, journal)
+	queue.Backoff = time.Millisecond
+	queue.MaxBackoff = 10 * time.Millisecond
+	queue.OnError = func(err *SinkError, entry *JournalEntry) {
+		if err.Sink != bad {
+			t.Errorf("OnError called with sink %v, want bad", err.Sink)
+		}
+		failed <- struct{}{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- queue.Run(ctx) }()
+
+	for _, no := range []string{"1", "2", "3"} {
+		report := createReportMessage()
+		report.MessageNo = no
+		if err := queue.Deliver(context.Background(), report); err != nil {
+			t.Fatalf("Deliver returned unexpected error: %v", err)
+		}
+	}
+
+	// The failing sink does not hold up the others.
+	if got := good.wait(t, 3); len(got) != 3 || got[0] != "1" || got[2] != "3" {
+		t.Errorf("Sink good received %v, want [1 2 3]", got)
+	}
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called")
+	}
+	bad.setFail(false)
+	if got := bad.wait(t, 3); len(got) != 3 || got[0] != "1" || got[2] != "3" {
+		t.Errorf("Sink bad received %v, want [1 2 3]", got)
+	}
+
+	// The consumed files are removed.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		files, _ := journalFiles(dir)
+		if len(files) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Journal has %d files, want 1", len(files))
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned unexpected error: %v", err)
+	}
+
+	// The cursors survive a restart.
+	restarted := newRecordingSink("good", false)
+	fanout = new(Fanout)
+	fanout.Add(restarted)
+	queue = NewSinkQueue(fanout, journal)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	report := createReportMessage()
+	report.MessageNo = "4"
+	queue.Deliver(context.Background(), report)
+	if got := restarted.wait(t, 1); len(got) != 1 || got[0] != "4" {
+		t.Errorf("Sink received %v after restart, want [4]", got)
+	}
+}
+
+func TestSinkQueue_skip(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		maxRetries int
+	}{
+		{"rejected", fmt.Errorf("%w: unexpected status code: 400", ErrSinkRejected), 0},
+		{"no-retry", errors.New("failure"), -1},
+	}
+
+	for _, tt := range tests {
+		journal, err := OpenJournal(filepath.Join(t.TempDir(), "queue"), 0)
+		if err != nil {
+			t.Fatalf("OpenJournal returned unexpected error: %v", err)
+		}
+		defer journal.Close()
+
+		var mu sync.Mutex
+		var attempts int
+		delivered := make(chan string, 10)
+		fanout := new(Fanout)
+		fanout.Add(SinkFunc(func(_ context.Context, report *ReportMessage) error {
+			if report.MessageNo == "1" {
+				mu.Lock()
+				attempts++
+				mu.Unlock()
+				return tt.err
+			}
+			delivered <- report.MessageNo
+			return nil
+		}))
+
+		queue := NewSinkQueue(fanout, journal)
+		queue.MaxRetries = tt.maxRetries
+		queue.Backoff = time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- queue.Run(ctx) }()
+
+		// The failing report is skipped, and does not hold up the next one.
+		for _, no := range []string{"1", "2"} {
+			report := createReportMessage()
+			report.MessageNo = no
+			queue.Deliver(context.Background(), report)
+		}
+		select {
+		case no := <-delivered:
+			if no != "2" {
+				t.Errorf("%s: Sink received %v, want 2", tt.name, no)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Sink did not receive the next report", tt.name)
+		}
+		cancel()
+		<-done
+
+		mu.Lock()
+		if attempts != 1 {
+			t.Errorf("%s: Sink was tried %d times, want 1", tt.name, attempts)
+		}
+		mu.Unlock()
+	}
+}
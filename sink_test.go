@@ -0,0 +1,199 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFanout(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string][]StatusCode)
+	sink := func(name string) Sink {
+		return SinkFunc(func(ctx context.Context, report *ReportMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], report.StatusCode)
+			return nil
+		})
+	}
+
+	fanout := new(Fanout)
+	fanout.Add(sink("all"))
+	fanout.Add(sink("deliveries"), DeliveryCategories...)
+	fanout.Add(sink("replies"), CategoryReply)
+
+	for _, code := range []StatusCode{StatusMessageReceived, StatusReplayContent, StatusMobileNumberNotExist} {
+		report := createReportMessage()
+		report.StatusCode = code
+		if err := fanout.Deliver(context.Background(), report); err != nil {
+			t.Fatalf("Deliver returned unexpected error: %v", err)
+		}
+	}
+
+	want := map[string][]StatusCode{
+		"all":        {StatusMessageReceived, StatusReplayContent, StatusMobileNumberNotExist},
+		"deliveries": {StatusMessageReceived, StatusMobileNumberNotExist},
+		"replies":    {StatusReplayContent},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fanout delivered %v, want %v", got, want)
+	}
+}
+
+func TestFanout_error(t *testing.T) {
+	failure := errors.New("failure")
+
+	fanout := new(Fanout)
+	fanout.Add(SinkFunc(func(ctx context.Context, report *ReportMessage) error { return nil }))
+	fanout.Add(SinkFunc(func(ctx context.Context, report *ReportMessage) error { return failure }))
+
+	err := fanout.Deliver(context.Background(), createReportMessage())
+	fanoutErr, ok := err.(*FanoutError)
+	if !ok || len(fanoutErr.Errors) != 1 || !errors.Is(fanoutErr.Errors[0], failure) {
+		t.Errorf("Deliver returned %v, want one %v", err, failure)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	_, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	attempts := 0
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		event := new(ReportEvent)
+		if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+			t.Errorf("Request body is not a ReportEvent: %v", err)
+		}
+		if event.Category != "success" || event.Report.BatchID != "00000000-0000-0000-0000-000000000000" {
+			t.Errorf("Request body is %+v", event)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization header is %q, want %q", got, "Bearer secret")
+		}
+	})
+
+	sink := &HTTPSink{
+		URL:     serverURL + "/reports",
+		Header:  http.Header{"Authorization": {"Bearer secret"}},
+		Backoff: time.Millisecond,
+	}
+	report := createReportMessage()
+	if err := sink.Deliver(context.Background(), report); err != nil {
+		t.Errorf("Deliver returned unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Deliver made %d attempts, want 3", attempts)
+	}
+}
+
+func TestHTTPSink_error(t *testing.T) {
+	_, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	attempts := map[string]int{}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		attempts[r.URL.Path]++
+		if r.URL.Path == "/bad-request" {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	tests := []struct {
+		path       string
+		maxRetries int
+		attempts   int
+	}{
+		{"/bad-request", 0, 1},
+		{"/unavailable", 2, 3},
+		{"/no-retry", -1, 1},
+	}
+	for _, tt := range tests {
+		sink := &HTTPSink{URL: serverURL + tt.path, MaxRetries: tt.maxRetries, Backoff: time.Millisecond}
+		err := sink.Deliver(context.Background(), createReportMessage())
+		if err == nil {
+			t.Errorf("Deliver to %s expected error to be returned", tt.path)
+		}
+		if rejected := errors.Is(err, ErrSinkRejected); rejected != (tt.path == "/bad-request") {
+			t.Errorf("Deliver to %s returned %v, rejected is %v", tt.path, err, rejected)
+		}
+		if attempts[tt.path] != tt.attempts {
+			t.Errorf("Deliver to %s made %d attempts, want %d", tt.path, attempts[tt.path], tt.attempts)
+		}
+	}
+}
+
+func readReportEvents(t *testing.T, path string) []*ReportEvent {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	var events []*ReportEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := new(ReportEvent)
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			t.Fatalf("Line %q is not a ReportEvent: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+
+	sink, err := OpenFileSink(path)
+	if err != nil {
+		t.Fatalf("OpenFileSink returned unexpected error: %v", err)
+	}
+	sink.Deliver(context.Background(), createReportMessage())
+	sink.Deliver(context.Background(), createReportMessage())
+	sink.Close()
+
+	events := readReportEvents(t, path)
+	if len(events) != 2 || events[0].Report.ReplyMessage != "Reply, Hello" || events[0].Category != "success" {
+		t.Errorf("File holds %+v, want 2 events", events)
+	}
+}
+
+func TestCommandSink(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+
+	sink := NewCommandSink("sh", "-c", `cat >> "$REPORTS"`)
+	sink.Env = []string{"REPORTS=" + path}
+	if err := sink.Deliver(context.Background(), createReportMessage()); err != nil {
+		t.Fatalf("Deliver returned unexpected error: %v", err)
+	}
+	if events := readReportEvents(t, path); len(events) != 1 || events[0].Report.MessageNo != "001" {
+		t.Errorf("Command received %+v, want the report", events)
+	}
+
+	sink = NewCommandSink("sh", "-c", "echo oops >&2; exit 3")
+	if err := sink.Deliver(context.Background(), createReportMessage()); err == nil || err.Error() != "exit status 3: oops" {
+		t.Errorf("Deliver returned %v, want exit status 3: oops", err)
+	}
+}
@@ -38,8 +38,8 @@ const (
 var statusText = map[StatusCode]string{
 	StatusInvalidMobileNumber:                  "無效門號",
 	StatusDTFormatErrorOrPassedMoreThan24Hours: "DT 格式錯誤或預計發送時間已過去 24小時以上",
-	StatusTheContentIsEmpty:                    "The content is empty.",
-	StatusNoMobile:                             "no mobile.",
+	StatusTheContentIsEmpty:                    "簡訊內容不得為空。",
+	StatusNoMobile:                             "無手機號碼。",
 	StatusServerSiteError:                      "主機端發生不明錯誤，請與廠商窗口聯繫。",
 	StatusWrongUsername:                        "無此帳號。",
 	StatusWrongPassword:                        "密碼錯誤。",
@@ -66,10 +66,132 @@ var statusText = map[StatusCode]string{
 	StatusReplayContent:                        "代表此呼叫為回覆簡訊之內容",
 }
 
-// Text returns status code text.
-func (c StatusCode) Text() string {
-	if str, ok := statusText[c]; ok {
+// StatusCategory classifies status codes.
+type StatusCategory int
+
+// List of status code categories.
+const (
+	// CategoryUnknown is the category of undeclared status codes.
+	CategoryUnknown StatusCategory = iota
+
+	// CategoryAPIError means the request was rejected by the API.
+	CategoryAPIError
+
+	// CategoryPending means the message has not reached a final state yet.
+	CategoryPending
+
+	// CategorySuccess means the message was received.
+	CategorySuccess
+
+	// CategoryFailure means the message could not be delivered.
+	CategoryFailure
+
+	// CategoryCanceled means the message was canceled before being sent.
+	CategoryCanceled
+
+	// CategoryTest means the message was accepted in testing mode and will not be delivered.
+	CategoryTest
+
+	// CategoryReply means the callback carries a reply message.
+	CategoryReply
+)
+
+var statusCategoryText = map[StatusCategory]string{
+	CategoryUnknown:  "unknown",
+	CategoryAPIError: "api_error",
+	CategoryPending:  "pending",
+	CategorySuccess:  "success",
+	CategoryFailure:  "failure",
+	CategoryCanceled: "canceled",
+	CategoryTest:     "test",
+	CategoryReply:    "reply",
+}
+
+func (c StatusCategory) String() string {
+	if str, ok := statusCategoryText[c]; ok {
 		return str
 	}
-	return "Unknown StatusCode"
+	return "unknown"
+}
+
+type statusMeta struct {
+	category StatusCategory
+
+	// retryable reports whether sending the same message again may succeed.
+	retryable bool
+}
+
+var statusMetadata = map[StatusCode]statusMeta{
+	StatusInvalidMobileNumber:                  {CategoryAPIError, false},
+	StatusDTFormatErrorOrPassedMoreThan24Hours: {CategoryAPIError, false},
+	StatusTheContentIsEmpty:                    {CategoryAPIError, false},
+	StatusNoMobile:                             {CategoryAPIError, false},
+	StatusServerSiteError:                      {CategoryAPIError, true},
+	StatusWrongUsername:                        {CategoryAPIError, false},
+	StatusWrongPassword:                        {CategoryAPIError, false},
+	StatusUsernameAndPasswordAreRequired:       {CategoryAPIError, false},
+	StatusSubjectRequired:                      {CategoryAPIError, false},
+	StatusImageRequired:                        {CategoryAPIError, false},
+	StatusImageTypeRequired:                    {CategoryAPIError, false},
+	StatusImageTooLarge:                        {CategoryAPIError, false},
+	StatusSent:                                 {CategoryPending, false},
+	StatusMessageReceived:                      {CategorySuccess, false},
+	StatusDeliveryFailureDueMobile:             {CategoryFailure, true},
+	StatusDeliveryFailureDueTelecom102:         {CategoryFailure, true},
+	StatusMobileNumberNotExist:                 {CategoryFailure, false},
+	StatusDeliveryFailureDueTelecom104:         {CategoryFailure, true},
+	StatusDeliveryFailureDueTelecom105:         {CategoryFailure, true},
+	StatusDeliveryFailureDueTelecom106:         {CategoryFailure, true},
+	StatusReceivedAfterDeadline:                {CategoryFailure, false},
+	StatusReservationSMS:                       {CategoryPending, false},
+	StatusNoCredit:                             {CategoryFailure, false},
+	StatusCanceled:                             {CategoryCanceled, false},
+	StatusInternationalSMSNotConfigured:        {CategoryFailure, false},
+	StatusSMSSent:                              {CategoryPending, false},
+	StatusTestingMode:                          {CategoryTest, false},
+	StatusReplayContent:                        {CategoryReply, false},
+}
+
+// Text returns status code text in the default language.
+func (c StatusCode) Text() string {
+	return c.TextIn(DefaultLanguage)
+}
+
+// Category returns the category of the status code.
+func (c StatusCode) Category() StatusCategory {
+	return statusMetadata[c].category
+}
+
+// IsSuccess reports whether the message was received.
+func (c StatusCode) IsSuccess() bool {
+	return c.Category() == CategorySuccess
+}
+
+// IsFailure reports whether the message could not be delivered.
+func (c StatusCode) IsFailure() bool {
+	return c.Category() == CategoryFailure
+}
+
+// IsPending reports whether the message has not reached a final state yet.
+func (c StatusCode) IsPending() bool {
+	return c.Category() == CategoryPending
+}
+
+// IsTerminal reports whether the delivery status will no longer change.
+func (c StatusCode) IsTerminal() bool {
+	switch c.Category() {
+	case CategorySuccess, CategoryFailure, CategoryCanceled, CategoryTest:
+		return true
+	}
+	return false
+}
+
+// IsAPIError reports whether the request was rejected by the API.
+func (c StatusCode) IsAPIError() bool {
+	return c.Category() == CategoryAPIError
+}
+
+// Retryable reports whether sending the same message again may succeed.
+func (c StatusCode) Retryable() bool {
+	return statusMetadata[c].retryable
 }
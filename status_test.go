@@ -1,6 +1,10 @@
 package every8d
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
 	"testing"
 )
 
@@ -17,9 +21,13 @@ func TestStatusCode_Text(t *testing.T) {
 			StatusCode(-100),
 			"無此帳號。",
 		},
+		{
+			StatusCode(-24),
+			"簡訊內容不得為空。",
+		},
 		{
 			StatusCode(100000),
-			"Unknown StatusCode",
+			"未知的狀態碼",
 		},
 	}
 
@@ -29,3 +37,120 @@ func TestStatusCode_Text(t *testing.T) {
 		}
 	}
 }
+
+// declaredStatusCodes parses status.go and returns every declared StatusCode constant.
+func declaredStatusCodes(t *testing.T) map[string]StatusCode {
+	f, err := parser.ParseFile(token.NewFileSet(), "status.go", nil, 0)
+	if err != nil {
+		t.Fatalf("ParseFile returned unexpected error: %v", err)
+	}
+
+	codes := map[string]StatusCode{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Values) != 1 {
+			return true
+		}
+		call, ok := spec.Values[0].(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return true
+		}
+		if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "StatusCode" {
+			return true
+		}
+
+		var lit string
+		switch arg := call.Args[0].(type) {
+		case *ast.BasicLit:
+			lit = arg.Value
+		case *ast.UnaryExpr:
+			lit = arg.Op.String() + arg.X.(*ast.BasicLit).Value
+		}
+		code, err := strconv.Atoi(lit)
+		if err != nil {
+			t.Fatalf("Unexpected StatusCode value %q", lit)
+		}
+		codes[spec.Names[0].Name] = StatusCode(code)
+		return true
+	})
+	return codes
+}
+
+func TestStatusCode_declared(t *testing.T) {
+	codes := declaredStatusCodes(t)
+	if len(codes) == 0 {
+		t.Fatal("No StatusCode constants found")
+	}
+	if got, want := len(statusMetadata), len(codes); got != want {
+		t.Errorf("statusMetadata has %d entries, want %d", got, want)
+	}
+
+	for name, code := range codes {
+		if _, ok := statusText[code]; !ok {
+			t.Errorf("%s has no text", name)
+		}
+		if code.Category() == CategoryUnknown {
+			t.Errorf("%s is not classified", name)
+		}
+		if code.Category().String() == "unknown" {
+			t.Errorf("%s category %d has no name", name, code.Category())
+		}
+
+		// Each code belongs to exactly one of the predicates.
+		n := 0
+		for _, ok := range []bool{code.IsSuccess(), code.IsFailure(), code.IsPending(), code.IsAPIError(),
+			code.Category() == CategoryCanceled, code.Category() == CategoryTest, code.Category() == CategoryReply} {
+			if ok {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("%s matches %d categories, want 1", name, n)
+		}
+	}
+}
+
+func TestStatusCode_classification(t *testing.T) {
+	tests := []struct {
+		in                                  StatusCode
+		category                            StatusCategory
+		success, failure, pending, terminal bool
+		apiError, retryable                 bool
+	}{
+		{StatusMessageReceived, CategorySuccess, true, false, false, true, false, false},
+		{StatusDeliveryFailureDueTelecom102, CategoryFailure, false, true, false, true, false, true},
+		{StatusMobileNumberNotExist, CategoryFailure, false, true, false, true, false, false},
+		{StatusSent, CategoryPending, false, false, true, false, false, false},
+		{StatusReservationSMS, CategoryPending, false, false, true, false, false, false},
+		{StatusCanceled, CategoryCanceled, false, false, false, true, false, false},
+		{StatusTestingMode, CategoryTest, false, false, false, true, false, false},
+		{StatusReplayContent, CategoryReply, false, false, false, false, false, false},
+		{StatusServerSiteError, CategoryAPIError, false, false, false, false, true, true},
+		{StatusWrongPassword, CategoryAPIError, false, false, false, false, true, false},
+		{StatusCode(100000), CategoryUnknown, false, false, false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.Category(); got != tt.category {
+			t.Errorf("%d.Category() returned %v, want %v", tt.in, got, tt.category)
+		}
+		if got := tt.in.IsSuccess(); got != tt.success {
+			t.Errorf("%d.IsSuccess() returned %v, want %v", tt.in, got, tt.success)
+		}
+		if got := tt.in.IsFailure(); got != tt.failure {
+			t.Errorf("%d.IsFailure() returned %v, want %v", tt.in, got, tt.failure)
+		}
+		if got := tt.in.IsPending(); got != tt.pending {
+			t.Errorf("%d.IsPending() returned %v, want %v", tt.in, got, tt.pending)
+		}
+		if got := tt.in.IsTerminal(); got != tt.terminal {
+			t.Errorf("%d.IsTerminal() returned %v, want %v", tt.in, got, tt.terminal)
+		}
+		if got := tt.in.IsAPIError(); got != tt.apiError {
+			t.Errorf("%d.IsAPIError() returned %v, want %v", tt.in, got, tt.apiError)
+		}
+		if got := tt.in.Retryable(); got != tt.retryable {
+			t.Errorf("%d.Retryable() returned %v, want %v", tt.in, got, tt.retryable)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package every8d
+
+import (
+	"strings"
+	"sync"
+)
+
+// List of languages with a built-in status text catalog.
+const (
+	LangTraditionalChinese = "zh-TW"
+	LangEnglish            = "en"
+)
+
+// DefaultLanguage is the language used by StatusCode.Text and
+// the fallback for languages without a catalog.
+const DefaultLanguage = LangTraditionalChinese
+
+var statusTextEnglish = map[StatusCode]string{
+	StatusInvalidMobileNumber:                  "Invalid mobile number.",
+	StatusDTFormatErrorOrPassedMoreThan24Hours: "Invalid DT format or the reservation time passed more than 24 hours ago.",
+	StatusTheContentIsEmpty:                    "The content is empty.",
+	StatusNoMobile:                             "No mobile number.",
+	StatusServerSiteError:                      "Unknown server error, please contact the vendor.",
+	StatusWrongUsername:                        "Wrong username.",
+	StatusWrongPassword:                        "Wrong password.",
+	StatusUsernameAndPasswordAreRequired:       "Username and password are required.",
+	StatusSubjectRequired:                      "Subject is required.",
+	StatusImageRequired:                        "Image is required.",
+	StatusImageTypeRequired:                    "Image file extension is required.",
+	StatusImageTooLarge:                        "Image is larger than 50K.",
+	StatusSent:                                 "Sent",
+	StatusMessageReceived:                      "Message received",
+	StatusDeliveryFailureDueMobile:             "Delivery failure due to the mobile",
+	StatusDeliveryFailureDueTelecom102:         "Delivery failure due to the telecom equipment",
+	StatusMobileNumberNotExist:                 "Mobile number does not exist",
+	StatusDeliveryFailureDueTelecom104:         "Delivery failure due to the telecom equipment",
+	StatusDeliveryFailureDueTelecom105:         "Delivery failure due to the telecom equipment",
+	StatusDeliveryFailureDueTelecom106:         "Delivery failure due to the telecom equipment",
+	StatusReceivedAfterDeadline:                "Received after the deadline",
+	StatusReservationSMS:                       "Reservation SMS",
+	StatusNoCredit:                             "No credit (or insufficient credit) to send",
+	StatusCanceled:                             "Canceled",
+	StatusInternationalSMSNotConfigured:        "International SMS is not enabled",
+	StatusSMSSent:                              "MMS sent",
+	StatusTestingMode:                          "Testing mode",
+	StatusReplayContent:                        "Reply message content",
+}
+
+// StatusCatalog holds the status code texts of a language.
+type StatusCatalog struct {
+	// Texts by status code.
+	Texts map[StatusCode]string
+
+	// Unknown is the text of undeclared status codes.
+	Unknown string
+}
+
+var (
+	statusCatalogsMu sync.RWMutex
+	statusCatalogs   = map[string]*StatusCatalog{
+		LangTraditionalChinese: {Texts: statusText, Unknown: "未知的狀態碼"},
+		LangEnglish:            {Texts: statusTextEnglish, Unknown: "Unknown StatusCode"},
+	}
+)
+
+// RegisterStatusCatalog registers the status code texts of a language.
+//
+// The catalog is merged into any catalog already registered for the language,
+// so it can be used to override individual texts of the built-in catalogs.
+func RegisterStatusCatalog(lang string, catalog StatusCatalog) {
+	statusCatalogsMu.Lock()
+	defer statusCatalogsMu.Unlock()
+
+	current, ok := statusCatalogs[lang]
+	if !ok {
+		current = &StatusCatalog{}
+		statusCatalogs[lang] = current
+	}
+
+	texts := make(map[StatusCode]string, len(current.Texts)+len(catalog.Texts))
+	for code, text := range current.Texts {
+		texts[code] = text
+	}
+	for code, text := range catalog.Texts {
+		texts[code] = text
+	}
+	current.Texts = texts
+
+	if catalog.Unknown != "" {
+		current.Unknown = catalog.Unknown
+	}
+}
+
+// StatusLanguages returns the languages with a registered status catalog.
+func StatusLanguages() []string {
+	statusCatalogsMu.RLock()
+	defer statusCatalogsMu.RUnlock()
+
+	var langs []string
+	for lang := range statusCatalogs {
+		langs = append(langs, lang)
+	}
+	return langs
+}
+
+// TextIn returns status code text in the given language, e.g. "en" or "zh-TW".
+//
+// If the language has no catalog, its base language (e.g. "en" for "en-US") is tried,
+// then the DefaultLanguage. Texts missing from the catalog are taken from the DefaultLanguage.
+func (c StatusCode) TextIn(lang string) string {
+	statusCatalogsMu.RLock()
+	defer statusCatalogsMu.RUnlock()
+
+	catalog := lookupStatusCatalog(lang)
+	if str, ok := catalog.Texts[c]; ok {
+		return str
+	}
+
+	// Fall back to the default language for texts missing from the catalog.
+	fallback := statusCatalogs[DefaultLanguage]
+	if str, ok := fallback.Texts[c]; ok {
+		return str
+	}
+	if catalog.Unknown != "" {
+		return catalog.Unknown
+	}
+	return fallback.Unknown
+}
+
+func lookupStatusCatalog(lang string) *StatusCatalog {
+	lang = strings.Replace(lang, "_", "-", -1)
+	for _, candidate := range []string{lang, strings.SplitN(lang, "-", 2)[0]} {
+		for key, catalog := range statusCatalogs {
+			if strings.EqualFold(key, candidate) {
+				return catalog
+			}
+		}
+	}
+	return statusCatalogs[DefaultLanguage]
+}
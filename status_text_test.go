@@ -0,0 +1,76 @@
+package every8d
+
+import (
+	"testing"
+)
+
+func TestStatusCode_TextIn(t *testing.T) {
+	tests := []struct {
+		in   StatusCode
+		lang string
+		out  string
+	}{
+		{StatusMessageReceived, LangTraditionalChinese, "發送成功"},
+		{StatusMessageReceived, LangEnglish, "Message received"},
+		{StatusMessageReceived, "en-US", "Message received"},
+		{StatusMessageReceived, "EN_gb", "Message received"},
+		{StatusMessageReceived, "ja", "發送成功"},
+		{StatusMessageReceived, "", "發送成功"},
+		{StatusCode(100000), LangEnglish, "Unknown StatusCode"},
+		{StatusCode(100000), "zh-tw", "未知的狀態碼"},
+	}
+
+	for i, tt := range tests {
+		if got, want := tt.in.TextIn(tt.lang), tt.out; got != want {
+			t.Errorf("TextIn %d. returned %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestStatusCode_TextIn_complete(t *testing.T) {
+	for _, lang := range []string{LangTraditionalChinese, LangEnglish} {
+		catalog := statusCatalogs[lang]
+		for name, code := range declaredStatusCodes(t) {
+			if _, ok := catalog.Texts[code]; !ok {
+				t.Errorf("%s has no %s text", name, lang)
+			}
+		}
+	}
+}
+
+func TestRegisterStatusCatalog(t *testing.T) {
+	RegisterStatusCatalog("ja", StatusCatalog{
+		Texts:   map[StatusCode]string{StatusMessageReceived: "送信成功"},
+		Unknown: "不明なステータスコード",
+	})
+	RegisterStatusCatalog(LangEnglish, StatusCatalog{
+		Texts: map[StatusCode]string{StatusCode(100000): "Custom"},
+	})
+	defer func() {
+		statusCatalogsMu.Lock()
+		delete(statusCatalogs, "ja")
+		delete(statusCatalogs[LangEnglish].Texts, StatusCode(100000))
+		statusCatalogsMu.Unlock()
+	}()
+
+	tests := []struct {
+		in   StatusCode
+		lang string
+		out  string
+	}{
+		{StatusMessageReceived, "ja-JP", "送信成功"},
+		{StatusSent, "ja", "已發送"},
+		{StatusCode(100001), "ja", "不明なステータスコード"},
+		{StatusCode(100000), LangEnglish, "Custom"},
+		{StatusMessageReceived, LangEnglish, "Message received"},
+	}
+
+	for i, tt := range tests {
+		if got, want := tt.in.TextIn(tt.lang), tt.out; got != want {
+			t.Errorf("TextIn %d. returned %v, want %v", i, got, want)
+		}
+	}
+	if got, want := len(StatusLanguages()), 3; got != want {
+		t.Errorf("StatusLanguages returned %d languages, want %d", got, want)
+	}
+}
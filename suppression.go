@@ -0,0 +1,350 @@
+package every8d
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultOptOutKeywords are the reply keywords commonly used to unsubscribe.
+var DefaultOptOutKeywords = []string{"STOP", "UNSUBSCRIBE", "退訂", "取消訂閱"}
+
+// ErrAllSuppressed is returned when every destination of a message is suppressed.
+// The returned SendResponse lists the suppressed numbers and nothing is sent.
+var ErrAllSuppressed = errors.New("all destinations are suppressed")
+
+// SuppressionList holds the mobile numbers that opted out. The client removes them
+// from the destination of every message, see Client.Suppression.
+//
+// The numbers are normalized with NormalizeMobile.
+type SuppressionList interface {
+	Contains(ctx context.Context, mobile string) (bool, error)
+	Add(ctx context.Context, mobile string) error
+	Remove(ctx context.Context, mobile string) error
+	List(ctx context.Context) ([]string, error)
+}
+
+// OptOutRule returns a KeywordRule opting out the numbers replying one of the
+// DefaultOptOutKeywords, with an optional confirmation reply. Set AutoResponder.OptOut
+// to the Add method of a SuppressionList to populate it:
+//
+//	responder, _ := every8d.NewAutoResponder(client, []*every8d.KeywordRule{every8d.OptOutRule("")})
+//	responder.OptOut = list.Add
+func OptOutRule(reply string) *KeywordRule {
+	return &KeywordRule{
+		Name:     "opt-out",
+		Match:    MatchExact,
+		Keywords: append([]string(nil), DefaultOptOutKeywords...),
+		Reply:    reply,
+		OptOut:   true,
+	}
+}
+
+// ImportSuppressionList adds the numbers read from r, one per line, to the list.
+// Blank lines and lines starting with # are skipped; only the first field of
+// comma separated lines is used. It returns the number of imported numbers.
+func ImportSuppressionList(ctx context.Context, list SuppressionList, r io.Reader) (int, error) {
+	var mobiles []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if mobile := NormalizeMobile(strings.SplitN(line, ",", 2)[0]); mobile != "" {
+			mobiles = append(mobiles, mobile)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if l, ok := list.(interface {
+		AddAll(ctx context.Context, mobiles []string) error
+	}); ok {
+		if err := l.AddAll(ctx, mobiles); err != nil {
+			return 0, err
+		}
+		return len(mobiles), nil
+	}
+	for i, mobile := range mobiles {
+		if err := list.Add(ctx, mobile); err != nil {
+			return i, err
+		}
+	}
+	return len(mobiles), nil
+}
+
+// ExportSuppressionList writes the numbers of the list to w, one per line.
+func ExportSuppressionList(ctx context.Context, list SuppressionList, w io.Writer) error {
+	mobiles, err := list.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mobile := range mobiles {
+		if _, err := fmt.Fprintln(w, mobile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type bypassSuppressionKey struct{}
+
+// bypassSuppression returns a copy of ctx sending to the suppressed numbers too, e.g.
+// the confirmation of an opt-out.
+func bypassSuppression(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassSuppressionKey{}, true)
+}
+
+// suppress splits the destination into the numbers to send to and the suppressed ones.
+func (c *Client) suppress(ctx context.Context, destination string) (string, []string, error) {
+	if bypass, _ := ctx.Value(bypassSuppressionKey{}).(bool); c.Suppression == nil || bypass {
+		return destination, nil, nil
+	}
+
+	var allowed, suppressed []string
+	for _, mobile := range strings.Split(destination, ",") {
+		if strings.TrimSpace(mobile) == "" {
+			continue
+		}
+		ok, err := c.Suppression.Contains(ctx, mobile)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			suppressed = append(suppressed, NormalizeMobile(mobile))
+		} else {
+			allowed = append(allowed, strings.TrimSpace(mobile))
+		}
+	}
+	return strings.Join(allowed, ","), suppressed, nil
+}
+
+// MemorySuppressionList is an in-memory SuppressionList.
+type MemorySuppressionList struct {
+	mu      sync.RWMutex
+	mobiles map[string]struct{}
+}
+
+// NewMemorySuppressionList returns a new MemorySuppressionList holding the numbers.
+func NewMemorySuppressionList(mobiles ...string) *MemorySuppressionList {
+	l := &MemorySuppressionList{mobiles: make(map[string]struct{})}
+	for _, mobile := range mobiles {
+		if mobile = NormalizeMobile(mobile); mobile != "" {
+			l.mobiles[mobile] = struct{}{}
+		}
+	}
+	return l
+}
+
+// Contains implements the SuppressionList interface.
+func (l *MemorySuppressionList) Contains(_ context.Context, mobile string) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, ok := l.mobiles[NormalizeMobile(mobile)]
+	return ok, nil
+}
+
+// Add implements the SuppressionList interface.
+func (l *MemorySuppressionList) Add(_ context.Context, mobile string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if mobile = NormalizeMobile(mobile); mobile != "" {
+		l.mobiles[mobile] = struct{}{}
+	}
+	return nil
+}
+
+// AddAll adds the numbers.
+func (l *MemorySuppressionList) AddAll(ctx context.Context, mobiles []string) error {
+	for _, mobile := range mobiles {
+		l.Add(ctx, mobile)
+	}
+	return nil
+}
+
+// Remove implements the SuppressionList interface.
+func (l *MemorySuppressionList) Remove(_ context.Context, mobile string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.mobiles, NormalizeMobile(mobile))
+	return nil
+}
+
+// List implements the SuppressionList interface. The numbers are sorted.
+func (l *MemorySuppressionList) List(_ context.Context) ([]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	mobiles := make([]string, 0, len(l.mobiles))
+	for mobile := range l.mobiles {
+		mobiles = append(mobiles, mobile)
+	}
+	sort.Strings(mobiles)
+	return mobiles, nil
+}
+
+// FileSuppressionList is a SuppressionList stored in an append-only text file, one
+// number per line, a removed number being a line prefixed with '-'. Each method first
+// reads the lines appended by other processes sharing the file, so their changes are
+// never overwritten.
+type FileSuppressionList struct {
+	*MemorySuppressionList
+
+	path   string
+	mu     sync.Mutex
+	offset int64
+}
+
+// OpenFileSuppressionList opens the list stored at path. A missing file is an empty list.
+func OpenFileSuppressionList(path string) (*FileSuppressionList, error) {
+	l := &FileSuppressionList{MemorySuppressionList: NewMemorySuppressionList(), path: path}
+	if err := l.refresh(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Contains implements the SuppressionList interface.
+func (l *FileSuppressionList) Contains(ctx context.Context, mobile string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return false, err
+	}
+	return l.MemorySuppressionList.Contains(ctx, mobile)
+}
+
+// Add implements the SuppressionList interface.
+func (l *FileSuppressionList) Add(ctx context.Context, mobile string) error {
+	return l.AddAll(ctx, []string{mobile})
+}
+
+// AddAll adds the numbers, appending them to the file at once.
+func (l *FileSuppressionList) AddAll(ctx context.Context, mobiles []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return err
+	}
+	var lines []string
+	for _, mobile := range mobiles {
+		if ok, _ := l.MemorySuppressionList.Contains(ctx, mobile); !ok {
+			if mobile = NormalizeMobile(mobile); mobile != "" {
+				lines = append(lines, mobile)
+			}
+		}
+	}
+	return l.append(lines)
+}
+
+// Remove implements the SuppressionList interface.
+func (l *FileSuppressionList) Remove(ctx context.Context, mobile string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return err
+	}
+	if ok, _ := l.MemorySuppressionList.Contains(ctx, mobile); !ok {
+		return nil
+	}
+	return l.append([]string{"-" + NormalizeMobile(mobile)})
+}
+
+// List implements the SuppressionList interface. The numbers are sorted.
+func (l *FileSuppressionList) List(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return nil, err
+	}
+	return l.MemorySuppressionList.List(ctx)
+}
+
+// append appends the lines to the file in a single write, then reads them back.
+// The caller must hold the lock.
+func (l *FileSuppressionList) append(lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return l.refresh()
+}
+
+// refresh applies the complete lines appended to the file since the last refresh.
+// The list is reloaded if the file was truncated. The caller must hold the lock.
+func (l *FileSuppressionList) refresh() error {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < l.offset {
+		l.MemorySuppressionList = NewMemorySuppressionList()
+		l.offset = 0
+	}
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			// A line without its newline is still being written.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l.offset += int64(len(line))
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			l.MemorySuppressionList.Remove(ctx, line[1:])
+			continue
+		}
+		l.MemorySuppressionList.Add(ctx, strings.SplitN(line, ",", 2)[0])
+	}
+}
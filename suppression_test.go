@@ -0,0 +1,198 @@
+package every8d
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMemorySuppressionList(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemorySuppressionList("0912345678")
+
+	l.Add(ctx, "886922333444")
+	l.Add(ctx, "+886933444555")
+	l.Remove(ctx, "0933-444-555")
+
+	for _, tt := range []struct {
+		mobile string
+		want   bool
+	}{
+		{"+886912345678", true},
+		{"0922333444", true},
+		{"+886933444555", false},
+	} {
+		if got, _ := l.Contains(ctx, tt.mobile); got != tt.want {
+			t.Errorf("Contains(%v) returned %v, want %v", tt.mobile, got, tt.want)
+		}
+	}
+
+	got, _ := l.List(ctx)
+	if want := []string{"+886912345678", "+886922333444"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List returned %v, want %v", got, want)
+	}
+}
+
+func TestFileSuppressionList(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "suppression.txt")
+
+	l, err := OpenFileSuppressionList(path)
+	if err != nil {
+		t.Fatalf("OpenFileSuppressionList returned unexpected error: %v", err)
+	}
+	n, err := ImportSuppressionList(ctx, l, strings.NewReader("# header\n0912345678,Alice\n\n0922333444\n"))
+	if err != nil || n != 2 {
+		t.Fatalf("ImportSuppressionList returned %d, %v, want 2, nil", n, err)
+	}
+	l.Add(ctx, "0933444555")
+	l.Remove(ctx, "0922333444")
+
+	l, err = OpenFileSuppressionList(path)
+	if err != nil {
+		t.Fatalf("OpenFileSuppressionList returned unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportSuppressionList(ctx, l, &buf); err != nil {
+		t.Fatalf("ExportSuppressionList returned unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "+886912345678\n+886933444555\n"; got != want {
+		t.Errorf("ExportSuppressionList wrote %q, want %q", got, want)
+	}
+}
+
+func TestFileSuppressionList_shared(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "suppression.txt")
+
+	// Two processes sharing the file, e.g. the webhook and the suppression command.
+	a, err := OpenFileSuppressionList(path)
+	if err != nil {
+		t.Fatalf("OpenFileSuppressionList returned unexpected error: %v", err)
+	}
+	b, err := OpenFileSuppressionList(path)
+	if err != nil {
+		t.Fatalf("OpenFileSuppressionList returned unexpected error: %v", err)
+	}
+
+	a.Add(ctx, "0912345678")
+	b.Add(ctx, "0922333444")
+	b.Remove(ctx, "0912345678")
+	a.Add(ctx, "0933444555")
+
+	for i, l := range []*FileSuppressionList{a, b} {
+		got, _ := l.List(ctx)
+		if want := []string{"+886922333444", "+886933444555"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("List %d. returned %v, want %v", i, got, want)
+		}
+	}
+	if ok, _ := a.Contains(ctx, "0912345678"); ok {
+		t.Error("Contains returned true for the number removed by the other list")
+	}
+}
+
+func TestClient_Send_suppression(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{
+			"MSG":  "Hello",
+			"DEST": "0922333444",
+		})
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+	mux.HandleFunc("/API21/HTTP/MMS/sendMMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("SendMMS called the API with all destinations suppressed")
+	})
+
+	client.Suppression = NewMemorySuppressionList("+886912345678")
+
+	got, err := client.Send(context.Background(), Message{Content: "Hello", Destination: "0912345678, 0922333444"})
+	if err != nil {
+		t.Fatalf("Send returned unexpected error: %v", err)
+	}
+	if want := []string{"+886912345678"}; !reflect.DeepEqual(got.Suppressed, want) {
+		t.Errorf("Send returned suppressed %v, want %v", got.Suppressed, want)
+	}
+
+	got, err = client.SendMMS(context.Background(), MMS{Destination: "0912345678"})
+	if err != ErrAllSuppressed {
+		t.Errorf("SendMMS returned error %v, want %v", err, ErrAllSuppressed)
+	}
+	if want := []string{"+886912345678"}; got == nil || !reflect.DeepEqual(got.Suppressed, want) {
+		t.Errorf("SendMMS returned %+v, want suppressed %v", got, want)
+	}
+}
+
+func TestOptOutRule(t *testing.T) {
+	ctx := context.Background()
+	list := NewMemorySuppressionList()
+
+	responder, err := NewAutoResponder(nil, []*KeywordRule{OptOutRule("")})
+	if err != nil {
+		t.Fatalf("NewAutoResponder returned unexpected error: %v", err)
+	}
+	responder.OptOut = list.Add
+
+	report := createReportMessage()
+	report.StatusCode = StatusReplayContent
+	report.ReplyMessage = "退訂"
+	if err := responder.HandleReply(ctx, report); err != nil {
+		t.Fatalf("HandleReply returned unexpected error: %v", err)
+	}
+
+	if ok, _ := list.Contains(ctx, report.Destination); !ok {
+		t.Errorf("%v was not added to the suppression list", report.Destination)
+	}
+}
+
+func TestOptOutRule_reply(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	sent := 0
+	mux.HandleFunc("/API21/HTTP/sendSMS.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{
+			"MSG":  "You have been unsubscribed.",
+			"DEST": "+886987654321",
+		})
+		sent++
+		fmt.Fprint(w, "87.00,1,1,0,00000000-0000-0000-0000-000000000000")
+	})
+
+	ctx := context.Background()
+	list := NewMemorySuppressionList()
+	client.Suppression = list
+
+	responder, err := NewAutoResponder(client, []*KeywordRule{OptOutRule("You have been unsubscribed.")})
+	if err != nil {
+		t.Fatalf("NewAutoResponder returned unexpected error: %v", err)
+	}
+	responder.OptOut = list.Add
+
+	// The confirmation reaches the number just suppressed, on each redelivery too.
+	report := createReportMessage()
+	report.StatusCode = StatusReplayContent
+	report.ReplyMessage = "STOP"
+	for i := 0; i < 2; i++ {
+		if err := responder.HandleReply(ctx, report); err != nil {
+			t.Fatalf("HandleReply returned unexpected error: %v", err)
+		}
+	}
+	if sent != 2 {
+		t.Errorf("Sent %d confirmations, want 2", sent)
+	}
+
+	// Other sends still skip the number.
+	if _, err := client.Send(ctx, Message{Content: "Hello", Destination: "0987654321"}); !errors.Is(err, ErrAllSuppressed) {
+		t.Errorf("Send returned %v, want %v", err, ErrAllSuppressed)
+	}
+}
@@ -0,0 +1,118 @@
+package every8d
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultWaitInitialInterval = 2 * time.Second
+	defaultWaitMaxInterval     = time.Minute
+	defaultWaitMultiplier      = 2.0
+)
+
+// WaitOptions specifies the optional parameters to the WaitForDelivery method.
+type WaitOptions struct {
+	// MMS polls the MMS delivery status instead of the SMS one.
+	MMS bool
+
+	// InitialInterval is the delay before the first poll is repeated.
+	// The interval is reset to this value whenever a poll makes progress.
+	// Defaults to 2 seconds.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the delay between polls. Defaults to 1 minute.
+	MaxInterval time.Duration
+
+	// Multiplier grows the delay after each poll without progress. Defaults to 2.
+	Multiplier float64
+
+	// Concurrency is the number of pages fetched in parallel for each poll.
+	Concurrency int
+}
+
+// WaitResult represents the result of waiting for a batch to be delivered.
+type WaitResult struct {
+	// Records of the last poll.
+	Records []DeliveryStatus
+
+	// Mobile numbers whose records have not reached a final state.
+	Pending []string
+}
+
+// Done reports whether every record reached a final state.
+func (r *WaitResult) Done() bool {
+	return len(r.Records) > 0 && len(r.Pending) == 0
+}
+
+// WaitForDelivery polls the delivery status of the batch until every record reaches a final state.
+//
+// The provided ctx bounds the wait. If it is canceled or time out, the result of the last poll
+// is returned along with ctx.Err(), so the caller can tell which numbers are still pending.
+func (c *Client) WaitForDelivery(ctx context.Context, batchID string, opts *WaitOptions) (*WaitResult, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	initial := opts.InitialInterval
+	if initial <= 0 {
+		initial = defaultWaitInitialInterval
+	}
+	max := opts.MaxInterval
+	if max <= 0 {
+		max = defaultWaitMaxInterval
+	}
+	multiplier := opts.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultWaitMultiplier
+	}
+
+	result := &WaitResult{}
+	interval := time.Duration(0)
+	terminated := 0
+
+	for {
+		it := c.newDeliveryStatusIterator(deliveryStatusURL(opts.MMS), batchID)
+		it.Concurrency = opts.Concurrency
+
+		records, err := it.All(ctx)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			default:
+			}
+			return result, err
+		}
+
+		result = newWaitResult(records)
+		if result.Done() {
+			return result, nil
+		}
+
+		// Back off while nothing changes, poll eagerly again once records progress.
+		if n := len(records) - len(result.Pending); interval == 0 || n > terminated {
+			interval = initial
+			terminated = n
+		} else if interval = time.Duration(float64(interval) * multiplier); interval > max {
+			interval = max
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func newWaitResult(records []DeliveryStatus) *WaitResult {
+	result := &WaitResult{Records: records}
+	for _, record := range records {
+		if !record.Status.IsTerminal() {
+			result.Pending = append(result.Pending, record.Mobile)
+		}
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WaitForDelivery(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var polls int32
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		status := StatusSent
+		if atomic.AddInt32(&polls, 1) > 2 {
+			status = StatusMessageReceived
+		}
+		fmt.Fprintf(w, `2
+	+886987654321	2017/12/18 23:14:17	1	%d
+	+886987654322	2017/12/18 23:14:18	1	101`, status)
+	})
+
+	got, err := client.WaitForDelivery(context.Background(), "00000000-0000-0000-0000-000000000000", &WaitOptions{
+		InitialInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("WaitForDelivery returned unexpected error: %v", err)
+	}
+	if !got.Done() {
+		t.Errorf("WaitForDelivery returned pending %v, want none", got.Pending)
+	}
+	if got, want := atomic.LoadInt32(&polls), int32(3); got != want {
+		t.Errorf("WaitForDelivery polled %d times, want %d", got, want)
+	}
+}
+
+func TestClient_WaitForDelivery_deadline(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/MMS/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `2
+	+886987654321	2017/12/18 23:14:17	1	700
+	+886987654322	2017/12/18 23:14:18	1	100`)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	got, err := client.WaitForDelivery(ctx, "00000000-0000-0000-0000-000000000000", &WaitOptions{
+		MMS:             true,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     10 * time.Millisecond,
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("WaitForDelivery returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if want := []string{"+886987654321"}; !reflect.DeepEqual(got.Pending, want) {
+		t.Errorf("WaitForDelivery returned pending %v, want %v", got.Pending, want)
+	}
+}
+
+func TestClient_WaitForDelivery_error(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+	})
+
+	_, err := client.WaitForDelivery(context.Background(), "00000000-0000-0000-0000-000000000000", nil)
+	if err, ok := err.(*ErrorResponse); !ok || err.ErrorCode != StatusServerSiteError {
+		t.Errorf("WaitForDelivery returned error %v, want ErrorResponse with %v", err, StatusServerSiteError)
+	}
+}
@@ -0,0 +1,202 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// ErrWatcherRun is returned by DeliveryWatcher.Run when it was already called.
+var ErrWatcherRun = errors.New("delivery watcher already run")
+
+// DeliveryEvent represents a change of the delivery status of a record.
+type DeliveryEvent struct {
+	BatchID string
+	Mobile  string
+
+	// Previous status. Records seen for the first time are reported with StatusSent,
+	// whatever their current status.
+	Old StatusCode
+
+	// Current status.
+	New StatusCode
+}
+
+// DeliveryWatcher periodically polls the delivery status of the registered batches
+// and emits a DeliveryEvent for each record whose status changed.
+//
+// A batch is unregistered automatically once all its records reached a final state.
+type DeliveryWatcher struct {
+	// Interval between polls. Defaults to 30 seconds.
+	Interval time.Duration
+
+	// Concurrency is the number of pages fetched in parallel for each batch.
+	Concurrency int
+
+	// OnError, if not nil, is called when polling a batch fails.
+	// The batch stays registered and is polled again on the next interval.
+	OnError func(batchID string, err error)
+
+	client  *Client
+	events  chan DeliveryEvent
+	runOnce sync.Once
+	mu      sync.Mutex
+	batches map[string]*watchedBatch
+}
+
+type watchedBatch struct {
+	mms      bool
+	statuses map[string]StatusCode
+}
+
+// NewDeliveryWatcher returns a new DeliveryWatcher polling on the given interval.
+func (c *Client) NewDeliveryWatcher(interval time.Duration) *DeliveryWatcher {
+	return &DeliveryWatcher{
+		Interval: interval,
+		client:   c,
+		events:   make(chan DeliveryEvent, 100),
+		batches:  make(map[string]*watchedBatch),
+	}
+}
+
+// Watch registers an SMS batch.
+func (w *DeliveryWatcher) Watch(batchID string) {
+	w.watch(batchID, false)
+}
+
+// WatchMMS registers an MMS batch.
+func (w *DeliveryWatcher) WatchMMS(batchID string) {
+	w.watch(batchID, true)
+}
+
+func (w *DeliveryWatcher) watch(batchID string, mms bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.batches[batchID]; !ok {
+		w.batches[batchID] = &watchedBatch{mms: mms, statuses: make(map[string]StatusCode)}
+	}
+}
+
+// Unwatch unregisters a batch.
+func (w *DeliveryWatcher) Unwatch(batchID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.batches, batchID)
+}
+
+// Watching returns the IDs of the registered batches.
+func (w *DeliveryWatcher) Watching() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var ids []string
+	for id := range w.batches {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Events returns the channel on which the status changes are delivered.
+// The channel is closed when Run returns.
+func (w *DeliveryWatcher) Events() <-chan DeliveryEvent {
+	return w.events
+}
+
+// Run polls the registered batches until ctx is canceled, then closes the events channel
+// and returns ctx.Err(). A watcher runs once, later calls return ErrWatcherRun.
+func (w *DeliveryWatcher) Run(ctx context.Context) error {
+	err := ErrWatcherRun
+	w.runOnce.Do(func() {
+		err = w.run(ctx)
+	})
+	return err
+}
+
+func (w *DeliveryWatcher) run(ctx context.Context) error {
+	defer close(w.events)
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll polls every registered batch once. It only returns an error if ctx is done.
+func (w *DeliveryWatcher) poll(ctx context.Context) error {
+	w.mu.Lock()
+	batches := make(map[string]*watchedBatch, len(w.batches))
+	for id, batch := range w.batches {
+		batches[id] = batch
+	}
+	w.mu.Unlock()
+
+	for batchID, batch := range batches {
+		it := w.client.newDeliveryStatusIterator(deliveryStatusURL(batch.mms), batchID)
+		it.Concurrency = w.Concurrency
+
+		records, err := it.All(ctx)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if w.OnError != nil {
+				w.OnError(batchID, err)
+			}
+			continue
+		}
+
+		terminal := len(records) > 0
+		for _, record := range records {
+			old, ok := batch.statuses[record.Mobile]
+			if !ok {
+				old = StatusSent
+			}
+			batch.statuses[record.Mobile] = record.Status
+
+			if !ok || old != record.Status {
+				event := DeliveryEvent{
+					BatchID: batchID,
+					Mobile:  record.Mobile,
+					Old:     old,
+					New:     record.Status,
+				}
+				select {
+				case w.events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if !record.Status.IsTerminal() {
+				terminal = false
+			}
+		}
+
+		if terminal {
+			w.Unwatch(batchID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package every8d
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeliveryWatcher(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var polls int32
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		switch atomic.AddInt32(&polls, 1) {
+		case 1:
+			fmt.Fprint(w, "2\n\t+886987654321\t2017/12/18 23:14:17\t1\t0\n\t+886987654322\t2017/12/18 23:14:17\t1\t0")
+		case 2:
+			fmt.Fprint(w, "2\n\t+886987654321\t2017/12/18 23:14:17\t1\t100\n\t+886987654322\t2017/12/18 23:14:17\t1\t0")
+		default:
+			fmt.Fprint(w, "2\n\t+886987654321\t2017/12/18 23:14:17\t1\t100\n\t+886987654322\t2017/12/18 23:14:17\t1\t101")
+		}
+	})
+	mux.HandleFunc("/API21/HTTP/MMS/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1\n\t+886987654323\t2017/12/18 23:14:17\t3\t303")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := client.NewDeliveryWatcher(time.Millisecond)
+	watcher.Watch("sms")
+	watcher.WatchMMS("mms")
+
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx) }()
+
+	got := map[string][]DeliveryEvent{}
+	for i := 0; i < 5; i++ {
+		event := <-watcher.Events()
+		got[event.BatchID] = append(got[event.BatchID], event)
+	}
+
+	want := map[string][]DeliveryEvent{
+		"sms": {
+			{BatchID: "sms", Mobile: "+886987654321", Old: StatusSent, New: StatusSent},
+			{BatchID: "sms", Mobile: "+886987654322", Old: StatusSent, New: StatusSent},
+			{BatchID: "sms", Mobile: "+886987654321", Old: StatusSent, New: StatusMessageReceived},
+			{BatchID: "sms", Mobile: "+886987654322", Old: StatusSent, New: StatusDeliveryFailureDueMobile},
+		},
+		"mms": {
+			{BatchID: "mms", Mobile: "+886987654323", Old: StatusSent, New: StatusCanceled},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Events returned %+v, want %+v", got, want)
+	}
+
+	// Both batches are terminal and should be unregistered.
+	deadline := time.Now().Add(time.Second)
+	for len(watcher.Watching()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if ids := watcher.Watching(); len(ids) > 0 {
+		t.Errorf("Watching returned %v, want none", ids)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want %v", err, context.Canceled)
+	}
+	if _, ok := <-watcher.Events(); ok {
+		t.Error("Events channel is not closed")
+	}
+	if err := watcher.Run(context.Background()); err != ErrWatcherRun {
+		t.Errorf("Run returned %v, want %v", err, ErrWatcherRun)
+	}
+}
+
+func TestDeliveryWatcher_error(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/API21/HTTP/getDeliveryStatus.ashx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "-99, 主機端發生不明錯誤，請與廠商窗口聯繫。")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	watcher := client.NewDeliveryWatcher(time.Millisecond)
+	watcher.OnError = func(batchID string, err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	watcher.Watch("sms")
+	go watcher.Run(ctx)
+
+	if err, ok := (<-errs).(*ErrorResponse); !ok || err.ErrorCode != StatusServerSiteError {
+		t.Errorf("OnError received %v, want ErrorResponse with %v", err, StatusServerSiteError)
+	}
+	if got, want := watcher.Watching(), []string{"sms"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Watching returned %v, want %v", got, want)
+	}
+}
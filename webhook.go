@@ -0,0 +1,194 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ReportHandlerFunc handles a ReportMessage received by a WebhookHandler.
+type ReportHandlerFunc func(ctx context.Context, report *ReportMessage) error
+
+// WebhookError reports an error caused by an EVERY8D callback request.
+type WebhookError struct {
+	// HTTP status code returned to EVERY8D.
+	// EVERY8D only retries a callback that was not answered with a 2xx status code,
+	// so malformed requests are rejected with 400 and handler failures with 500.
+	StatusCode int
+
+	// Parsed report, nil if the request could not be parsed.
+	Report *ReportMessage
+
+	Err error
+}
+
+func (e *WebhookError) Error() string {
+	return fmt.Sprintf("webhook: %d %v", e.StatusCode, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookHandler is an http.Handler receiving the EVERY8D sending reports and reply messages.
+//
+//	http.Handle("/callback", &every8d.WebhookHandler{
+//		OnDeliveryReport: func(ctx context.Context, report *every8d.ReportMessage) error {
+//			// Process sending report...
+//			return nil
+//		},
+//		OnReply: func(ctx context.Context, report *every8d.ReportMessage) error {
+//			// Process reply message...
+//			return nil
+//		},
+//	})
+type WebhookHandler struct {
+	// Authenticator, if not nil, rejects the callbacks failing authentication
+	// before they are parsed.
+	Authenticator *WebhookAuthenticator
+
+	// MaxBodySize, if positive, limits the size in bytes of the request bodies.
+	// Larger callbacks are rejected with 413 Request Entity Too Large.
+	MaxBodySize int64
+
+	// Journal, if not nil, durably records every accepted report passing the Deduplicator
+	// before it is handled. The duplicates are not recorded, but a report whose handler
+	// failed is recorded again when it is redelivered.
+	Journal *Journal
+
+	// Events, if not nil, receives every accepted report passing the Deduplicator,
+	// e.g. to watch them live.
+	Events *EventStream
+
+	// Metrics, if not nil, observes every accepted report passing the Deduplicator.
+	Metrics Metrics
+
+	// Deduplicator, if not nil, detects the reports delivered more than once.
+	Deduplicator *Deduplicator
+
+	// OnDeliveryReport is called for sending reports.
+	OnDeliveryReport ReportHandlerFunc
+
+	// OnReply is called for reply messages, whose status code is StatusReplayContent.
+	OnReply ReportHandlerFunc
+
+	// ErrorHandler is called with a *WebhookError when a callback fails.
+	// It is responsible for writing the response.
+	// If nil, the status code of the *WebhookError is written.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		h.error(w, r, &WebhookError{
+			StatusCode: http.StatusMethodNotAllowed,
+			Err:        fmt.Errorf("method %s not allowed", r.Method),
+		})
+		return
+	}
+
+	if h.Authenticator != nil {
+		if err := h.Authenticator.Authenticate(r); err != nil {
+			h.error(w, r, &WebhookError{StatusCode: authStatusCode(err), Err: err})
+			return
+		}
+	}
+
+	if h.MaxBodySize > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, h.MaxBodySize)
+	}
+
+	report, err := ParseReportMessage(r)
+	if err != nil {
+		code := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		h.error(w, r, &WebhookError{StatusCode: code, Err: err})
+		return
+	}
+
+	ctx := r.Context()
+	duplicate := false
+	if h.Deduplicator != nil {
+		if duplicate, err = h.Deduplicator.Claim(report); err != nil {
+			h.error(w, r, &WebhookError{StatusCode: http.StatusInternalServerError, Report: report, Err: err})
+			return
+		}
+		if duplicate {
+			if !h.Deduplicator.DeliverDuplicates {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			ctx = withDuplicate(ctx)
+		}
+	}
+
+	if h.Journal != nil && !duplicate {
+		if _, err := h.Journal.Append(report); err != nil {
+			h.error(w, r, &WebhookError{StatusCode: http.StatusInternalServerError, Report: report, Err: h.release(report, err)})
+			return
+		}
+	}
+
+	if h.Metrics != nil {
+		h.Metrics.ObserveReport(report)
+	}
+
+	if h.Events != nil {
+		h.Events.Publish(ctx, report)
+	}
+
+	if err := h.dispatch(ctx, report); err != nil {
+		if !duplicate {
+			err = h.release(report, err)
+		}
+		h.error(w, r, &WebhookError{StatusCode: http.StatusInternalServerError, Report: report, Err: err})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// release releases the claim of a failed callback, so its retries are not dropped.
+func (h *WebhookHandler) release(report *ReportMessage, err error) error {
+	if h.Deduplicator == nil {
+		return err
+	}
+	if releaseErr := h.Deduplicator.Release(report); releaseErr != nil {
+		return errors.Join(err, releaseErr)
+	}
+	return err
+}
+
+// dispatch calls the handler of the report, recovering from panics.
+func (h *WebhookHandler) dispatch(ctx context.Context, report *ReportMessage) (err error) {
+	fn := h.OnDeliveryReport
+	if report.StatusCode == StatusReplayContent {
+		fn = h.OnReply
+	}
+	if fn == nil {
+		return nil
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return fn(ctx, report)
+}
+
+func (h *WebhookHandler) error(w http.ResponseWriter, r *http.Request, err *WebhookError) {
+	if h.ErrorHandler != nil {
+		h.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(err.StatusCode), err.StatusCode)
+}
@@ -0,0 +1,175 @@
+package every8d
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultTokenParam = "token"
+
+// Errors returned by WebhookAuthenticator.Authenticate.
+var (
+	ErrInvalidWebhookToken     = errors.New("invalid webhook token")
+	ErrForbiddenWebhookAddress = errors.New("forbidden webhook source address")
+)
+
+// WebhookAuthenticator authenticates EVERY8D callback requests, which carry no credentials
+// of their own, by a secret token embedded in the callback URL and by the source address.
+type WebhookAuthenticator struct {
+	// Token is the secret expected either as the last segment of the callback URL path,
+	// e.g. /callback/<token>, or as the TokenParam query parameter. Empty disables the check.
+	Token string
+
+	// TokenParam is the query parameter carrying the token. Defaults to "token".
+	TokenParam string
+
+	// AllowedNetworks restricts the source addresses of callbacks. Empty allows any address.
+	AllowedNetworks []*net.IPNet
+
+	// TrustedProxies are the networks of reverse proxies whose X-Forwarded-For header is trusted
+	// to carry the source address.
+	TrustedProxies []*net.IPNet
+
+	invalidToken     uint64
+	forbiddenAddress uint64
+}
+
+// WebhookRejections counts the callback requests rejected by a WebhookAuthenticator.
+type WebhookRejections struct {
+	InvalidToken     uint64
+	ForbiddenAddress uint64
+}
+
+// ParseCIDRs parses a list of CIDR notation networks, e.g. "203.0.113.0/24".
+// A single IP address is treated as a network of that address only.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: cidr}
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Authenticate checks the token and the source address of the request.
+func (a *WebhookAuthenticator) Authenticate(r *http.Request) error {
+	if a.Token != "" && !a.validToken(r) {
+		atomic.AddUint64(&a.invalidToken, 1)
+		return ErrInvalidWebhookToken
+	}
+	if len(a.AllowedNetworks) > 0 && !containsIP(a.AllowedNetworks, a.SourceIP(r)) {
+		atomic.AddUint64(&a.forbiddenAddress, 1)
+		return ErrForbiddenWebhookAddress
+	}
+	return nil
+}
+
+// Handler returns a handler passing the authenticated requests to h, e.g. to protect
+// the other endpoints of a webhook server with the token of the callbacks. The others
+// are answered with 401 Unauthorized or 403 Forbidden.
+func (a *WebhookAuthenticator) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Authenticate(r); err != nil {
+			code := authStatusCode(err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authStatusCode returns the status code of an error returned by Authenticate.
+func authStatusCode(err error) int {
+	if err == ErrInvalidWebhookToken {
+		return http.StatusUnauthorized
+	}
+	return http.StatusForbidden
+}
+
+// Rejections returns the number of rejected requests.
+func (a *WebhookAuthenticator) Rejections() WebhookRejections {
+	return WebhookRejections{
+		InvalidToken:     atomic.LoadUint64(&a.invalidToken),
+		ForbiddenAddress: atomic.LoadUint64(&a.forbiddenAddress),
+	}
+}
+
+func (a *WebhookAuthenticator) validToken(r *http.Request) bool {
+	param := a.TokenParam
+	if param == "" {
+		param = defaultTokenParam
+	}
+
+	token := []byte(a.Token)
+	valid := subtle.ConstantTimeCompare([]byte(path.Base(r.URL.Path)), token) == 1
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get(param)), token) == 1 {
+		valid = true
+	}
+	return valid
+}
+
+// SourceIP returns the source address of the request. The X-Forwarded-For header is
+// only followed through the TrustedProxies.
+func (a *WebhookAuthenticator) SourceIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(a.TrustedProxies, ip) {
+		return ip
+	}
+
+	// Walk the proxies from the nearest one, the first untrusted address is the source.
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			return nil
+		}
+		ip = hop
+		if !containsIP(a.TrustedProxies, ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package every8d
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	networks, err := ParseCIDRs("203.0.113.0/24", " 198.51.100.7 ", "", "2001:db8::/32")
+	if err != nil {
+		t.Fatalf("ParseCIDRs returned unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, network := range networks {
+		got = append(got, network.String())
+	}
+	want := []string{"203.0.113.0/24", "198.51.100.7/32", "2001:db8::/32"}
+	if len(got) != len(want) {
+		t.Fatalf("ParseCIDRs returned %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseCIDRs returned %v, want %v", got, want)
+		}
+	}
+
+	if _, err := ParseCIDRs("invalid"); err == nil {
+		t.Error("Expected error to be returned")
+	}
+	if _, err := ParseCIDRs("10.0.0.0/33"); err == nil {
+		t.Error("Expected error to be returned")
+	}
+}
+
+func TestWebhookAuthenticator_token(t *testing.T) {
+	a := &WebhookAuthenticator{Token: "secret"}
+
+	tests := []struct {
+		target string
+		want   error
+	}{
+		{"/callback/secret", nil},
+		{"/callback?token=secret", nil},
+		{"/callback", ErrInvalidWebhookToken},
+		{"/callback/secre", ErrInvalidWebhookToken},
+		{"/callback?token=secret1", ErrInvalidWebhookToken},
+	}
+
+	for _, tt := range tests {
+		if got := a.Authenticate(httptest.NewRequest(http.MethodGet, tt.target, nil)); got != tt.want {
+			t.Errorf("Authenticate(%v) returned %v, want %v", tt.target, got, tt.want)
+		}
+	}
+	if got, want := a.Rejections(), (WebhookRejections{InvalidToken: 3}); got != want {
+		t.Errorf("Rejections returned %+v, want %+v", got, want)
+	}
+
+	a.TokenParam = "key"
+	if err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/callback?key=secret", nil)); err != nil {
+		t.Errorf("Authenticate returned unexpected error: %v", err)
+	}
+}
+
+func TestWebhookAuthenticator_address(t *testing.T) {
+	allowed, _ := ParseCIDRs("203.0.113.0/24")
+	proxies, _ := ParseCIDRs("10.0.0.0/8")
+	a := &WebhookAuthenticator{AllowedNetworks: allowed, TrustedProxies: proxies}
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		want         error
+		wantSourceIP string
+	}{
+		{"203.0.113.1:1234", "", nil, "203.0.113.1"},
+		{"198.51.100.1:1234", "", ErrForbiddenWebhookAddress, "198.51.100.1"},
+		// Untrusted peers can't spoof the header.
+		{"198.51.100.1:1234", "203.0.113.1", ErrForbiddenWebhookAddress, "198.51.100.1"},
+		{"10.0.0.1:1234", "203.0.113.1", nil, "203.0.113.1"},
+		{"10.0.0.1:1234", "203.0.113.1, 10.0.0.2", nil, "203.0.113.1"},
+		{"10.0.0.1:1234", "203.0.113.1, 198.51.100.1", ErrForbiddenWebhookAddress, "198.51.100.1"},
+		{"10.0.0.1:1234", "", ErrForbiddenWebhookAddress, "10.0.0.1"},
+		{"10.0.0.1:1234", "invalid", ErrForbiddenWebhookAddress, "<nil>"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/callback", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+
+		if got := a.Authenticate(r); got != tt.want {
+			t.Errorf("Authenticate(%v, %v) returned %v, want %v", tt.remoteAddr, tt.forwardedFor, got, tt.want)
+		}
+		if got := a.SourceIP(r); got.String() != tt.wantSourceIP {
+			t.Errorf("SourceIP(%v, %v) returned %v, want %v", tt.remoteAddr, tt.forwardedFor, got, tt.wantSourceIP)
+		}
+	}
+	if got, want := a.Rejections(), (WebhookRejections{ForbiddenAddress: 5}); got != want {
+		t.Errorf("Rejections returned %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookHandler_authenticator(t *testing.T) {
+	allowed := []*net.IPNet{{IP: net.IPv4(192, 0, 2, 0), Mask: net.CIDRMask(24, 32)}}
+	h := &WebhookHandler{
+		Authenticator: &WebhookAuthenticator{Token: "secret", AllowedNetworks: allowed},
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/callback/secret", http.StatusOK},
+		{"/callback/invalid", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		r := newCallbackRequest(createReportMessage())
+		r.URL.Path = tt.target
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Code; got != tt.want {
+			t.Errorf("ServeHTTP(%v) returned status %d, want %d", tt.target, got, tt.want)
+		}
+	}
+
+	r := newCallbackRequest(createReportMessage())
+	r.URL.Path = "/callback/secret"
+	r.RemoteAddr = "198.51.100.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusForbidden; got != want {
+		t.Errorf("ServeHTTP returned status %d, want %d", got, want)
+	}
+}
+
+func TestWebhookAuthenticator_Handler(t *testing.T) {
+	auth := &WebhookAuthenticator{Token: "secret"}
+	h := auth.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/events?token=secret", http.StatusNoContent},
+		{"/events", http.StatusUnauthorized},
+		{"/events?token=wrong", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s returned status %d, want %d", tt.target, w.Code, tt.want)
+		}
+	}
+}
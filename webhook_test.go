@@ -0,0 +1,138 @@
+package every8d
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-querystring/query"
+)
+
+func newCallbackRequest(report *ReportMessage) *http.Request {
+	q, _ := query.Values(report)
+	return httptest.NewRequest(http.MethodGet, "/callback?"+q.Encode(), nil)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var reports, replies []*ReportMessage
+	h := &WebhookHandler{
+		OnDeliveryReport: func(ctx context.Context, report *ReportMessage) error {
+			reports = append(reports, report)
+			return nil
+		},
+		OnReply: func(ctx context.Context, report *ReportMessage) error {
+			replies = append(replies, report)
+			return nil
+		},
+	}
+
+	report := createReportMessage()
+	reply := createReportMessage()
+	reply.StatusCode = StatusReplayContent
+
+	for _, in := range []*ReportMessage{report, reply} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newCallbackRequest(in))
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Errorf("ServeHTTP returned status %d, want %d", got, want)
+		}
+	}
+
+	if want := []*ReportMessage{report}; !reflect.DeepEqual(reports, want) {
+		t.Errorf("OnDeliveryReport received %v, want %v", reports, want)
+	}
+	if want := []*ReportMessage{reply}; !reflect.DeepEqual(replies, want) {
+		t.Errorf("OnReply received %v, want %v", replies, want)
+	}
+}
+
+func TestWebhookHandler_error(t *testing.T) {
+	invalid := createReportMessage()
+	invalid.BatchID = ""
+
+	tests := []struct {
+		handler ReportHandlerFunc
+		req     *http.Request
+		want    int
+	}{
+		{nil, newCallbackRequest(createReportMessage()), http.StatusOK},
+		{nil, newCallbackRequest(invalid), http.StatusBadRequest},
+		{nil, httptest.NewRequest(http.MethodPut, "/callback", nil), http.StatusMethodNotAllowed},
+		{
+			func(ctx context.Context, report *ReportMessage) error { return errors.New("failure") },
+			newCallbackRequest(createReportMessage()),
+			http.StatusInternalServerError,
+		},
+		{
+			func(ctx context.Context, report *ReportMessage) error { panic("failure") },
+			newCallbackRequest(createReportMessage()),
+			http.StatusInternalServerError,
+		},
+	}
+
+	for i, tt := range tests {
+		h := &WebhookHandler{OnDeliveryReport: tt.handler}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, tt.req)
+		if got := w.Code; got != tt.want {
+			t.Errorf("ServeHTTP %d. returned status %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookHandler_maxBodySize(t *testing.T) {
+	h := &WebhookHandler{MaxBodySize: 256}
+
+	tests := []struct {
+		padding int
+		want    int
+	}{
+		{0, http.StatusOK},
+		{256, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		values := reportValues(createReportMessage())
+		values.Set("padding", strings.Repeat("x", tt.padding))
+		req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if got := w.Code; got != tt.want {
+			t.Errorf("ServeHTTP with %d bytes of padding returned status %d, want %d", tt.padding, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookHandler_errorHandler(t *testing.T) {
+	var got *WebhookError
+	h := &WebhookHandler{
+		OnDeliveryReport: func(ctx context.Context, report *ReportMessage) error {
+			panic("failure")
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err.(*WebhookError)
+			w.WriteHeader(http.StatusAccepted)
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newCallbackRequest(createReportMessage()))
+
+	if got, want := w.Code, http.StatusAccepted; got != want {
+		t.Errorf("ServeHTTP returned status %d, want %d", got, want)
+	}
+	if got == nil {
+		t.Fatal("ErrorHandler was not called")
+	}
+	if got.StatusCode != http.StatusInternalServerError || got.Report == nil {
+		t.Errorf("ErrorHandler received %+v", got)
+	}
+	if want := "webhook: 500 panic: failure"; got.Error() != want {
+		t.Errorf("Error returned %v, want %v", got.Error(), want)
+	}
+}